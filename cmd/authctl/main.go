@@ -0,0 +1,111 @@
+// Command authctl manages the refresh token sessions stored in Postgres.
+//
+// Usage:
+//
+//	authctl [-config path] [-format table|json] <command> [flags]
+//
+// Commands:
+//
+//	sessions -user <id> [-all]                    list the sessions of a user
+//	revoke   -user <id> [-user-agent <ua>]        revoke the sessions of a user
+//	revoke   -session <id>                        revoke a single session and its rotated tokens
+//	purge    -keep <duration> [-idle 0]           delete revoked, used and idle sessions
+//
+// Revoked access tokens are added to the access token denylist, so the
+// running services stop accepting them within seconds.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/passwordhash/jwt-test-task/internal/config"
+	"github.com/passwordhash/jwt-test-task/internal/storage/postgres/denylist"
+	"github.com/passwordhash/jwt-test-task/internal/storage/postgres/tokens"
+	"github.com/passwordhash/jwt-test-task/pkg/postgres"
+)
+
+const usage = `usage: authctl [-config path] [-format table|json] <command> [flags]
+
+commands:
+  sessions   list the sessions of a user
+  revoke     revoke sessions by user, user and user agent, or session ID
+  purge      delete revoked, rotated and idle sessions
+
+Run "authctl <command> -h" for the command flags.
+`
+
+type command func(ctx context.Context, e *env, args []string) error
+
+// env holds the dependencies of the commands.
+type env struct {
+	tokens   *tokens.Storage
+	denylist *denylist.Storage
+	// denylistTTL must match the one of the services, see app.New.
+	denylistTTL time.Duration
+	out         *printer
+}
+
+func main() {
+	format := flag.String("format", "table", "output format: table or json")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+	}
+
+	// MustLoad registers the config flag and parses the command line.
+	cfg := config.MustLoad()
+
+	commands := map[string]command{
+		"sessions": runSessions,
+		"revoke":   runRevoke,
+		"purge":    runPurge,
+	}
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	run, ok := commands[args[0]]
+	if !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	out, err := newPrinter(os.Stdout, *format)
+	if err != nil {
+		fail(err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	pool, err := postgres.NewPool(ctx, cfg.PG.DSN(), postgres.WithMaxConns(1))
+	if err != nil {
+		fail(fmt.Errorf("failed to connect to postgres: %w", err))
+	}
+	defer pool.Close()
+
+	e := &env{
+		tokens:      tokens.New(pool),
+		denylist:    denylist.New(pool),
+		denylistTTL: cfg.App.AccessTTL + cfg.JWT.Leeway,
+		out:         out,
+	}
+
+	if err := run(ctx, e, args[1:]); err != nil {
+		pool.Close()
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "authctl:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/passwordhash/jwt-test-task/internal/domain/models"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	if format != formatTable && format != formatJSON {
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+
+	return &printer{w: w, format: format}, nil
+}
+
+// session is the printed form of a refresh token. The token hash is left out.
+// SessionID is the token family, which revoke -session takes.
+type session struct {
+	ID        string     `json:"id"`
+	SessionID string     `json:"session_id"`
+	UserID    string     `json:"user_id"`
+	TokenID   string     `json:"token_id"`
+	UserAgent string     `json:"user_agent"`
+	IP        string     `json:"ip"`
+	Status    string     `json:"status"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func newSession(t models.RefreshToken) session {
+	status := "active"
+	switch {
+	case t.IsRevoked:
+		status = "revoked"
+	case t.UsedAt != nil:
+		status = "used"
+	}
+
+	return session{
+		ID:        t.ID,
+		SessionID: t.FamilyID,
+		UserID:    t.UserID,
+		TokenID:   t.TokenID,
+		UserAgent: t.UserAgent,
+		IP:        t.IP,
+		Status:    status,
+		UsedAt:    t.UsedAt,
+		CreatedAt: t.CreatedAt,
+		UpdatedAt: t.UpdatedAt,
+	}
+}
+
+func (p *printer) sessions(sessions []session) error {
+	if p.format == formatJSON {
+		return p.json(sessions)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSESSION\tSTATUS\tIP\tUSER AGENT\tCREATED\tUPDATED")
+	for _, s := range sessions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			s.ID, s.SessionID, s.Status, s.IP, truncate(s.UserAgent, 50),
+			s.CreatedAt.Format(time.DateTime), s.UpdatedAt.Format(time.DateTime))
+	}
+
+	return tw.Flush()
+}
+
+// result prints the outcome of a command that changes sessions.
+func (p *printer) result(action string, count int64, tokenIDs []string) error {
+	if p.format == formatJSON {
+		return p.json(struct {
+			Action   string   `json:"action"`
+			Count    int64    `json:"count"`
+			TokenIDs []string `json:"token_ids,omitempty"`
+		}{action, count, tokenIDs})
+	}
+
+	_, err := fmt.Fprintf(p.w, "%s: %d session(s)\n", action, count)
+	if err != nil || len(tokenIDs) == 0 {
+		return err
+	}
+
+	_, err = fmt.Fprintf(p.w, "access token ids: %s\n", strings.Join(tokenIDs, ", "))
+	return err
+}
+
+func (p *printer) json(v any) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+)
+
+func runPurge(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ExitOnError)
+	var (
+		keep = fs.Duration("keep", 0, "required, keep revoked and rotated sessions updated within this period; "+
+			"reuse of a purged rotated token is no longer detected, so keep them at least as long as "+
+			"refresh tokens are expected to be used")
+		idle = fs.Duration("idle", 0, "also delete active sessions not refreshed for this period; 0 keeps them")
+	)
+	_ = fs.Parse(args)
+
+	// Rotated tokens are what reuse detection and the refresh grace period
+	// work with, so they are never purged without an explicit retention.
+	if *keep <= 0 {
+		fs.Usage()
+		return errors.New("-keep is required and must be positive")
+	}
+
+	if *idle < 0 {
+		return errors.New("-idle must not be negative")
+	}
+
+	deleted, err := e.tokens.Purge(ctx, *keep, *idle)
+	if err != nil {
+		return fmt.Errorf("failed to purge sessions: %w", err)
+	}
+
+	return e.out.result("purged", deleted, nil)
+}
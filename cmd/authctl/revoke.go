@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	repoErr "github.com/passwordhash/jwt-test-task/internal/storage/errors"
+)
+
+func runRevoke(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("revoke", flag.ExitOnError)
+	var (
+		userID    = fs.String("user", "", "revoke every session of the user")
+		userAgent = fs.String("user-agent", "", "with -user, revoke only the sessions of this user agent")
+		sessionID = fs.String("session", "", "revoke the session with this ID, as shown in the SESSION column")
+	)
+	_ = fs.Parse(args)
+
+	var (
+		tokenIDs []string
+		err      error
+	)
+
+	switch {
+	case *sessionID != "" && *userID == "":
+		// The whole family is revoked, so the access tokens issued before
+		// the last refresh of the session are denylisted too.
+		tokenIDs, err = e.tokens.RevokeFamily(ctx, *sessionID)
+	case *sessionID != "":
+		return errors.New("-session can't be combined with -user")
+	case *userID != "" && *userAgent != "":
+		tokenIDs, err = e.tokens.Revoke(ctx, *userID, *userAgent)
+	case *userID != "":
+		tokenIDs, err = e.tokens.RevokeAll(ctx, *userID)
+	default:
+		fs.Usage()
+		return errors.New("one of -user or -session is required")
+	}
+
+	if errors.Is(err, repoErr.ErrRefreshTokenNotFound) {
+		return e.out.result("revoked", 0, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	if err := e.denylist.Add(ctx, tokenIDs, e.denylistTTL); err != nil {
+		return fmt.Errorf("sessions revoked, but their access tokens were not denylisted: %w", err)
+	}
+
+	return e.out.result("revoked", int64(len(tokenIDs)), tokenIDs)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+)
+
+func runSessions(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("sessions", flag.ExitOnError)
+	var (
+		userID = fs.String("user", "", "user ID")
+		all    = fs.Bool("all", false, "include revoked and rotated sessions")
+	)
+	_ = fs.Parse(args)
+
+	if *userID == "" {
+		fs.Usage()
+		return errors.New("-user is required")
+	}
+
+	rows, err := e.tokens.ByUserID(ctx, *userID)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	sessions := make([]session, 0, len(rows))
+	for _, t := range rows {
+		s := newSession(t)
+		if !*all && s.Status != "active" {
+			continue
+		}
+
+		sessions = append(sessions, s)
+	}
+
+	return e.out.sessions(sessions)
+}
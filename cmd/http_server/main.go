@@ -36,6 +36,11 @@ func main() {
 	defer cancel()
 
 	application.HTTPSrv.Stop(shutdownCtx)
+	application.Denylist.Stop(shutdownCtx)
+
+	if application.Dispatcher != nil {
+		application.Dispatcher.Stop(shutdownCtx)
+	}
 
 	log.Info("application stopped gracefully")
 }
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/passwordhash/jwt-test-task/pkg/jwt"
+)
+
+// timeClaims are printed in human readable form as well.
+var timeClaims = []string{"iat", "nbf", "exp"}
+
+func runDecode(args []string) error {
+	fs := flag.NewFlagSet("decode", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: jwtctl decode [token]")
+		fmt.Fprintln(fs.Output(), "Prints the token header and claims. The signature is NOT verified.")
+	}
+	_ = fs.Parse(args)
+
+	token, err := tokenArg(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	if jwt.IsEncrypted(token) {
+		return decodeEncrypted(token)
+	}
+
+	header, claims, err := jwt.Decode(token)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Header:")
+	if err := printJSON(header); err != nil {
+		return err
+	}
+
+	fmt.Println("Claims:")
+	if err := printJSON(claims); err != nil {
+		return err
+	}
+
+	printTimes(claims, time.Now())
+
+	return nil
+}
+
+// decodeEncrypted prints the header of a JWE. The claims can't be read
+// without the decryption key.
+func decodeEncrypted(token string) error {
+	encodedHeader, _, _ := strings.Cut(token, ".")
+
+	decoded, err := base64.RawURLEncoding.DecodeString(encodedHeader)
+	if err != nil {
+		return fmt.Errorf("invalid JWE header: %w", err)
+	}
+
+	var header jwt.JWEHeader
+	if err := json.Unmarshal(decoded, &header); err != nil {
+		return fmt.Errorf("invalid JWE header: %w", err)
+	}
+
+	fmt.Println("Encrypted token (JWE), the claims can't be shown without the key.")
+	fmt.Println("Header:")
+
+	return printJSON(header)
+}
+
+func printTimes(claims jwt.Payload, now time.Time) {
+	var lines []string
+
+	for _, name := range timeClaims {
+		raw, ok := claims[name].(float64)
+		if !ok {
+			continue
+		}
+
+		t := time.Unix(int64(raw), 0)
+		lines = append(lines, fmt.Sprintf("  %s: %s (%s)", name, t.Format(time.RFC3339), relative(t, now)))
+	}
+
+	if len(lines) == 0 {
+		return
+	}
+
+	fmt.Println("Times:")
+	fmt.Println(strings.Join(lines, "\n"))
+
+	if exp, ok := claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0)) {
+		fmt.Println("The token is EXPIRED.")
+	}
+}
+
+func relative(t, now time.Time) string {
+	d := t.Sub(now).Round(time.Second)
+	if d < 0 {
+		return (-d).String() + " ago"
+	}
+
+	return "in " + d.String()
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/passwordhash/jwt-test-task/pkg/jwt"
+)
+
+const hmacSecretSize = 64
+
+var curves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+func runKeygen(args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: jwtctl keygen -type hmac|rsa|ec|ed25519 [flags]")
+		fs.PrintDefaults()
+	}
+
+	var (
+		keyType = fs.String("type", "", "key type: hmac, rsa, ec or ed25519")
+		bits    = fs.Int("bits", 3072, "RSA key size")
+		curve   = fs.String("curve", "P-256", "EC curve: P-256, P-384 or P-521")
+		alg     = fs.String("alg", "", "algorithm for the JWK alg field (default: picked from the key type)")
+		format  = fs.String("format", "pem", "output format: pem or jwk")
+		kid     = fs.String("kid", "", "key ID for the JWK kid field")
+	)
+	_ = fs.Parse(args)
+
+	material, err := generateKey(*keyType, *bits, *curve)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "pem":
+		return writePEM(material)
+	case "jwk":
+		return writeJWK(material, jwt.Alg(*alg), *kid)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+}
+
+func generateKey(keyType string, bits int, curveName string) (any, error) {
+	switch keyType {
+	case "hmac":
+		secret := make([]byte, hmacSecretSize)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+
+		return secret, nil
+	case "rsa":
+		return rsa.GenerateKey(rand.Reader, bits)
+	case "ec":
+		curve, ok := curves[curveName]
+		if !ok {
+			return nil, fmt.Errorf("unknown curve %q", curveName)
+		}
+
+		return ecdsa.GenerateKey(curve, rand.Reader)
+	case "ed25519":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("unknown key type %q", keyType)
+	}
+}
+
+// writePEM prints the PKCS #8 private key followed by the PKIX public key.
+// An HMAC secret has no PEM form, so it is printed base64url encoded; the
+// service takes it as is through JWT_SECRET.
+func writePEM(material any) error {
+	if secret, ok := material.([]byte); ok {
+		fmt.Println(base64.RawURLEncoding.EncodeToString(secret))
+		return nil
+	}
+
+	private, err := x509.MarshalPKCS8PrivateKey(material)
+	if err != nil {
+		return err
+	}
+
+	public, err := x509.MarshalPKIXPublicKey(material.(crypto.Signer).Public())
+	if err != nil {
+		return err
+	}
+
+	if err := pem.Encode(os.Stdout, &pem.Block{Type: "PRIVATE KEY", Bytes: private}); err != nil {
+		return err
+	}
+
+	return pem.Encode(os.Stdout, &pem.Block{Type: "PUBLIC KEY", Bytes: public})
+}
+
+func writeJWK(material any, alg jwt.Alg, kid string) error {
+	alg, err := jwt.AlgForKey(material, alg)
+	if err != nil {
+		return err
+	}
+
+	key, err := jwt.NewSigningKey(alg, material)
+	if err != nil {
+		return err
+	}
+
+	private, err := jwt.NewPrivateJWK(kid, key)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Private:")
+	if err := printJSON(private); err != nil {
+		return err
+	}
+
+	// A symmetric key has no public part to publish.
+	if _, ok := material.([]byte); ok {
+		return nil
+	}
+
+	public, err := jwt.NewJWK(kid, key.Public())
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Public:")
+	return printJSON(public)
+}
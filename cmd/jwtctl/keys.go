@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto"
+	"fmt"
+	"os"
+
+	"github.com/passwordhash/jwt-test-task/pkg/jwt"
+)
+
+// loadSigningKey creates a signing key from an HMAC secret or a PEM private
+// key file. An empty alg picks the algorithm from the key type.
+func loadSigningKey(secret, keyPath string, alg jwt.Alg) (jwt.SigningKey, error) {
+	material, err := loadKeyMaterial(secret, keyPath, jwt.ParsePrivateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	alg, err = jwt.AlgForKey(material, alg)
+	if err != nil {
+		return nil, err
+	}
+
+	return jwt.NewSigningKey(alg, material)
+}
+
+// loadVerifyingKey creates a verifying key from an HMAC secret or a PEM key
+// file. The file may hold a public or a private key, so a token can be
+// checked with the same file it was signed with.
+func loadVerifyingKey(secret, keyPath string, alg jwt.Alg) (jwt.VerifyingKey, error) {
+	material, err := loadKeyMaterial(secret, keyPath, parseAnyKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	if signer, ok := material.(crypto.Signer); ok {
+		material = signer.Public()
+	}
+
+	alg, err = jwt.AlgForKey(material, alg)
+	if err != nil {
+		return nil, err
+	}
+
+	return jwt.NewVerifyingKey(alg, material)
+}
+
+func loadKeyMaterial(secret, keyPath string, parse func([]byte) (any, error)) (any, error) {
+	switch {
+	case secret != "" && keyPath != "":
+		return nil, fmt.Errorf("-secret and -key are mutually exclusive")
+	case secret != "":
+		return []byte(secret), nil
+	case keyPath != "":
+		data, err := os.ReadFile(keyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		return parse(data)
+	default:
+		return nil, fmt.Errorf("one of -secret or -key is required")
+	}
+}
+
+func parseAnyKeyPEM(data []byte) (any, error) {
+	if key, err := jwt.ParsePublicKeyPEM(data); err == nil {
+		return key, nil
+	}
+
+	return jwt.ParsePrivateKeyPEM(data)
+}
@@ -0,0 +1,82 @@
+// Command jwtctl mints, decodes and verifies tokens and generates keys.
+//
+// Usage:
+//
+//	jwtctl decode [token]
+//	jwtctl verify -secret <secret> | -key <file> | -jwks <url> [token]
+//	jwtctl sign -claims <file> -secret <secret> | -key <file> [-ttl 15m] [-kid id]
+//	jwtctl keygen -type hmac|rsa|ec|ed25519 [-format pem|jwk]
+//
+// A token argument of "-" or no argument at all reads the token from stdin.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const usage = `usage: jwtctl <command> [flags]
+
+commands:
+  decode   print the header and claims of a token without verifying it
+  verify   verify a token with a secret, a key file or a JWKS URL
+  sign     sign claims from a JSON file
+  keygen   generate a signing key
+
+Run "jwtctl <command> -h" for the command flags.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	commands := map[string]func(args []string) error{
+		"decode": runDecode,
+		"verify": runVerify,
+		"sign":   runSign,
+		"keygen": runKeygen,
+	}
+
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err := run(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "jwtctl:", err)
+		os.Exit(1)
+	}
+}
+
+// tokenArg returns the token from the arguments, or reads it from stdin.
+func tokenArg(args []string) (string, error) {
+	if len(args) > 0 && args[0] != "-" {
+		return strings.TrimSpace(args[0]), nil
+	}
+
+	token, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read token from stdin: %w", err)
+	}
+
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", fmt.Errorf("no token given")
+	}
+
+	return token, nil
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
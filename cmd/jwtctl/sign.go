@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/passwordhash/jwt-test-task/pkg/jwt"
+)
+
+func runSign(args []string) error {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: jwtctl sign -claims <file> -secret <secret> | -key <file> [flags]")
+		fs.PrintDefaults()
+	}
+
+	var (
+		claimsPath = fs.String("claims", "", "JSON file with the claims, \"-\" for stdin")
+		secret     = fs.String("secret", "", "HMAC secret")
+		keyPath    = fs.String("key", "", "PEM private key file")
+		alg        = fs.String("alg", "", "signing algorithm (default: picked from the key type)")
+		kid        = fs.String("kid", "", "key ID to put into the kid header")
+		ttl        = fs.Duration("ttl", 0, "set iat to now and exp to now+ttl; 0 keeps the claims as given")
+	)
+	_ = fs.Parse(args)
+
+	if *claimsPath == "" {
+		fs.Usage()
+		return fmt.Errorf("-claims is required")
+	}
+
+	claims, err := readClaims(*claimsPath)
+	if err != nil {
+		return err
+	}
+
+	key, err := loadSigningKey(*secret, *keyPath, jwt.Alg(*alg))
+	if err != nil {
+		return err
+	}
+
+	if *kid != "" {
+		key = jwt.WithKID(*kid, key)
+	}
+
+	var token string
+	if *ttl > 0 {
+		token, err = jwt.NewToken(claims, *ttl, key)
+	} else {
+		token, err = jwt.NewTokenFrom(claims, key)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(token)
+
+	return nil
+}
+
+func readClaims(path string) (jwt.Payload, error) {
+	var (
+		data []byte
+		err  error
+	)
+
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read claims: %w", err)
+	}
+
+	claims := jwt.Payload{}
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, fmt.Errorf("claims must be a JSON object: %w", err)
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/passwordhash/jwt-test-task/pkg/jwt"
+)
+
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: jwtctl verify -secret <secret> | -key <file> | -jwks <url> [flags] [token]")
+		fs.PrintDefaults()
+	}
+
+	var (
+		secret  = fs.String("secret", "", "HMAC secret")
+		keyPath = fs.String("key", "", "PEM public or private key file")
+		jwksURL = fs.String("jwks", "", "JWKS URL to fetch the verification keys from")
+		alg     = fs.String("alg", "", "expected algorithm (default: derived from the key)")
+		iss     = fs.String("iss", "", "required issuer")
+		aud     = fs.String("aud", "", "required audience")
+		leeway  = fs.Duration("leeway", 0, "allowed clock skew")
+		timeout = fs.Duration("timeout", 10*time.Second, "JWKS fetch timeout")
+	)
+	_ = fs.Parse(args)
+
+	token, err := tokenArg(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	header, _, err := jwt.Decode(token)
+	if err != nil {
+		return err
+	}
+
+	opts := []jwt.ParseOption{jwt.WithLeeway(*leeway)}
+	if *alg != "" {
+		opts = append(opts, jwt.WithAllowedAlgs(jwt.Alg(*alg)))
+	}
+	if *iss != "" {
+		opts = append(opts, jwt.WithIssuer(*iss))
+	}
+	if *aud != "" {
+		opts = append(opts, jwt.WithAudience(*aud))
+	}
+
+	claims, err := verify(token, *secret, *keyPath, *jwksURL, jwt.Alg(*alg), header.Alg, *timeout, opts)
+	if err != nil {
+		return fmt.Errorf("token is NOT valid: %w", err)
+	}
+
+	fmt.Println("Token is valid.")
+	fmt.Println("Header:")
+	if err := printJSON(header); err != nil {
+		return err
+	}
+
+	fmt.Println("Claims:")
+	return printJSON(claims)
+}
+
+// verify checks the token with the key from -secret, -key or -jwks. The
+// algorithm always comes from the key: a JWKS entry carries its own, and a
+// local key gets alg, or, without it, the header algorithm only if it
+// belongs to the key type. A token can't make an RSA public key be used
+// as an HMAC secret this way.
+func verify(
+	token, secret, keyPath, jwksURL string,
+	alg, headerAlg jwt.Alg,
+	timeout time.Duration,
+	opts []jwt.ParseOption,
+) (jwt.Payload, error) {
+	if jwksURL == "" {
+		if alg == "" {
+			alg = headerAlg
+		}
+
+		key, err := loadVerifyingKey(secret, keyPath, alg)
+		if err != nil {
+			return nil, err
+		}
+
+		return jwt.ParseToken(token, key, opts...)
+	}
+
+	if secret != "" || keyPath != "" {
+		return nil, fmt.Errorf("-jwks can't be combined with -secret or -key")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	verifier, err := jwt.NewRemoteVerifier(ctx, jwksURL, jwt.WithParseOptions(opts...))
+	if err != nil {
+		return nil, err
+	}
+	defer verifier.Close()
+
+	return verifier.Verify(ctx, token)
+}
@@ -6,13 +6,20 @@ import (
 
 	httpApp "github.com/passwordhash/jwt-test-task/internal/app/http"
 	"github.com/passwordhash/jwt-test-task/internal/config"
+	"github.com/passwordhash/jwt-test-task/internal/denylist"
 	authSvc "github.com/passwordhash/jwt-test-task/internal/service/auth"
+	denylistStorage "github.com/passwordhash/jwt-test-task/internal/storage/postgres/denylist"
+	outboxStorage "github.com/passwordhash/jwt-test-task/internal/storage/postgres/outbox"
 	authStorage "github.com/passwordhash/jwt-test-task/internal/storage/postgres/tokens"
+	"github.com/passwordhash/jwt-test-task/internal/webhook"
 	postgresPkg "github.com/passwordhash/jwt-test-task/pkg/postgres"
 )
 
 type App struct {
-	HTTPSrv *httpApp.App
+	HTTPSrv  *httpApp.App
+	Denylist *denylist.Denylist
+	// Dispatcher is nil when no webhook URL is configured.
+	Dispatcher *webhook.Dispatcher
 }
 
 func New(
@@ -31,13 +38,62 @@ func New(
 
 	authStg := authStorage.New(postgresPool)
 
+	// Access tokens are accepted until exp plus the leeway, the denylist
+	// entries must outlive them.
+	accessDenylist := denylist.New(
+		log.WithGroup("denylist"),
+		denylistStorage.New(postgresPool),
+		cfg.App.AccessTTL+cfg.JWT.Leeway,
+	)
+	if err := accessDenylist.Start(ctx); err != nil {
+		panic("failed to start access token denylist: " + err.Error())
+	}
+
+	var (
+		svcOpts = []authSvc.Option{
+			authSvc.WithRevocationCacheTTL(cfg.App.RevocationCacheTTL),
+			authSvc.WithDenylist(accessDenylist),
+			authSvc.WithMaxSessions(cfg.App.MaxSessions),
+			authSvc.WithRefreshGracePeriod(cfg.App.RefreshGracePeriod),
+			authSvc.WithIssuer(cfg.JWT.Issuer),
+			authSvc.WithAudience(cfg.JWT.Audience),
+			authSvc.WithLeeway(cfg.JWT.Leeway),
+		}
+		dispatcher *webhook.Dispatcher
+	)
+
+	if encKey, decKey := mustLoadEncryptionKey(cfg.JWT); encKey != nil {
+		svcOpts = append(svcOpts, authSvc.WithEncryption(encKey, decKey))
+	}
+	if cfg.Webhook.URL != "" {
+		if cfg.Webhook.Secret == "" {
+			panic("webhook secret is required when webhook url is set")
+		}
+
+		svcOpts = append(svcOpts, authSvc.WithNewIPEvents(), authSvc.WithReuseEvents())
+
+		dispatcher = webhook.NewDispatcher(
+			log.WithGroup("webhook"),
+			cfg.Webhook,
+			outboxStorage.New(postgresPool),
+			webhook.New(cfg.Webhook.URL, cfg.Webhook.Secret, cfg.Webhook.Timeout),
+		)
+		dispatcher.Start()
+	}
+
 	authService := authSvc.New(
 		log.WithGroup("service"),
 		authStg,
-		authSvc.RefreshTokenManager{},
+		authStg,
+		authStg,
+		authSvc.NewRefreshTokenManager(
+			[]byte(cfg.App.RefreshTokenSecret),
+			authSvc.WithHasher(mustNewHasher(cfg.RefreshHash)),
+		),
 		authStg,
 		cfg.App.AccessTTL,
-		cfg.App.JWTSecret,
+		mustLoadKeyring(cfg.JWT),
+		svcOpts...,
 	)
 
 	httpSrv := httpApp.New(
@@ -48,6 +104,8 @@ func New(
 	)
 
 	return &App{
-		HTTPSrv: httpSrv,
+		HTTPSrv:    httpSrv,
+		Denylist:   accessDenylist,
+		Dispatcher: dispatcher,
 	}
 }
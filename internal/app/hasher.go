@@ -0,0 +1,42 @@
+package app
+
+import (
+	"github.com/passwordhash/jwt-test-task/internal/config"
+	"github.com/passwordhash/jwt-test-task/pkg/hasher"
+)
+
+const hashKeyLen = 32
+
+// mustNewHasher creates the hasher of refresh token secrets described by
+// the config. It panics on an unknown algorithm or invalid parameters.
+func mustNewHasher(cfg config.RefreshHashConfig) hasher.Hasher {
+	var h hasher.Hasher
+
+	switch hasher.Algorithm(cfg.Alg) {
+	case hasher.Bcrypt:
+		h = hasher.BcryptHasher{Cost: cfg.BcryptCost}
+	case hasher.Argon2id:
+		h = hasher.Argon2idHasher{
+			Time:    cfg.Argon2Time,
+			Memory:  cfg.Argon2Memory,
+			Threads: cfg.Argon2Threads,
+			KeyLen:  hashKeyLen,
+		}
+	case hasher.Scrypt:
+		h = hasher.ScryptHasher{
+			N:      cfg.ScryptN,
+			R:      cfg.ScryptR,
+			P:      cfg.ScryptP,
+			KeyLen: hashKeyLen,
+		}
+	default:
+		panic("unknown refresh token hash algorithm: " + cfg.Alg)
+	}
+
+	// Hashing once checks the parameters before the first request does.
+	if _, err := h.Hash("check"); err != nil {
+		panic("invalid refresh token hash parameters: " + err.Error())
+	}
+
+	return h
+}
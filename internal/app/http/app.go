@@ -10,6 +10,7 @@ import (
 
 	"github.com/passwordhash/jwt-test-task/internal/config"
 	authHandler "github.com/passwordhash/jwt-test-task/internal/handler/api/v1/auth"
+	wellknownHandler "github.com/passwordhash/jwt-test-task/internal/handler/wellknown"
 	authSvc "github.com/passwordhash/jwt-test-task/internal/service/auth"
 )
 
@@ -63,9 +64,12 @@ func (a *App) Run() error {
 
 	mux := http.NewServeMux()
 
-	authHlr := authHandler.New(a.authSvc, a.authSvc)
+	authHlr := authHandler.New(a.authSvc, a.authSvc, a.authSvc, a.authSvc)
 	authHlr.RegisterRoutes(mux)
 
+	wellknownHlr := wellknownHandler.New(a.authSvc)
+	wellknownHlr.RegisterRoutes(mux)
+
 	srv := &http.Server{ //nolint:exhaustruct
 		Addr:         ":" + strconv.Itoa(a.port),
 		Handler:      mux,
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"os"
+
+	"github.com/passwordhash/jwt-test-task/internal/config"
+	"github.com/passwordhash/jwt-test-task/pkg/jwt"
+)
+
+// mustLoadKeyring creates the access token keyring from the config. It
+// panics if a key can't be loaded or there is no key to sign tokens with.
+func mustLoadKeyring(cfg config.JWTConfig) *jwt.Keyring {
+	keyring := jwt.NewKeyring()
+	alg := jwt.Alg(cfg.Alg)
+
+	var keyMaterial any
+	switch {
+	case cfg.PrivateKeyPath != "":
+		data, err := os.ReadFile(cfg.PrivateKeyPath)
+		if err != nil {
+			panic("failed to read jwt private key: " + err.Error())
+		}
+
+		keyMaterial, err = jwt.ParsePrivateKeyPEM(data)
+		if err != nil {
+			panic("failed to parse jwt private key: " + err.Error())
+		}
+	case cfg.Secret != "":
+		keyMaterial = []byte(cfg.Secret)
+	}
+
+	if keyMaterial != nil {
+		key, err := jwt.NewSigningKey(alg, keyMaterial)
+		if err != nil {
+			panic("failed to create jwt signing key: " + err.Error())
+		}
+
+		if err := keyring.AddSigningKey(cfg.KID, key); err != nil {
+			panic("failed to add jwt signing key: " + err.Error())
+		}
+	}
+
+	if cfg.KeysDir != "" {
+		if err := keyring.LoadDir(cfg.KeysDir, alg); err != nil {
+			panic("failed to load jwt keys: " + err.Error())
+		}
+	}
+
+	activeKID := cfg.ActiveKID
+	if activeKID == "" {
+		activeKID = cfg.KID
+	}
+
+	if err := keyring.SetActive(activeKID); err != nil {
+		panic("failed to set active jwt key: " + err.Error())
+	}
+
+	for _, kid := range cfg.RetiredKIDs {
+		if err := keyring.Retire(kid); err != nil {
+			panic("failed to retire jwt key: " + err.Error())
+		}
+	}
+
+	return keyring
+}
+
+// mustLoadEncryptionKey creates the access token encryption key from the
+// config. It returns nil if encryption is disabled and panics if the key
+// can't be loaded.
+func mustLoadEncryptionKey(cfg config.JWTConfig) (jwt.EncryptionKey, jwt.DecryptionKey) {
+	switch jwt.KeyAlg(cfg.EncryptionAlg) {
+	case "":
+		return nil, nil
+	case jwt.Direct:
+		secret, err := base64.StdEncoding.DecodeString(cfg.EncryptionKey)
+		if err != nil {
+			panic("failed to decode jwt encryption key: " + err.Error())
+		}
+
+		key, err := jwt.NewDirectKey(secret)
+		if err != nil {
+			panic("failed to create jwt encryption key: " + err.Error())
+		}
+
+		return key, key
+	case jwt.RSAOAEP256:
+		data, err := os.ReadFile(cfg.EncryptionKeyPath)
+		if err != nil {
+			panic("failed to read jwt encryption key: " + err.Error())
+		}
+
+		parsed, err := jwt.ParsePrivateKeyPEM(data)
+		if err != nil {
+			panic("failed to parse jwt encryption key: " + err.Error())
+		}
+
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			panic("jwt encryption key is not an RSA key")
+		}
+
+		key, err := jwt.NewRSAOAEPDecryptionKey(rsaKey)
+		if err != nil {
+			panic("failed to create jwt encryption key: " + err.Error())
+		}
+
+		return key.Public(), key
+	default:
+		panic("unsupported jwt encryption algorithm: " + cfg.EncryptionAlg)
+	}
+}
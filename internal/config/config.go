@@ -10,15 +10,71 @@ import (
 )
 
 type Config struct {
-	App  AppConfig      `yaml:"app"`
-	HTTP HTTPConfig     `yaml:"http"`
-	PG   PostgresConfig `yaml:"postgres"`
+	App         AppConfig         `yaml:"app"`
+	HTTP        HTTPConfig        `yaml:"http"`
+	JWT         JWTConfig         `yaml:"jwt"`
+	RefreshHash RefreshHashConfig `yaml:"refresh_hash"`
+	PG          PostgresConfig    `yaml:"postgres"`
+	Webhook     WebhookConfig     `yaml:"webhook"`
 }
 
 type AppConfig struct {
 	Env       string        `env:"ENV" yaml:"env" env-required:"true"`
-	JWTSecret string        `env:"JWT_SECRET" env-required:"true"`
 	AccessTTL time.Duration `env:"ACCESS_TTL" yaml:"access_ttl" env-required:"true"`
+
+	// RefreshTokenSecret authenticates refresh tokens, see
+	// auth.RefreshTokenManager.
+	RefreshTokenSecret string `env:"REFRESH_TOKEN_SECRET" env-required:"true"`
+
+	RevocationCacheTTL time.Duration `env:"REVOCATION_CACHE_TTL" yaml:"revocation_cache_ttl" env-default:"30s"`
+	// RefreshGracePeriod is how long a rotated refresh token returns the
+	// same successor pair instead of being treated as reused.
+	RefreshGracePeriod time.Duration `env:"REFRESH_GRACE_PERIOD" yaml:"refresh_grace_period" env-default:"10s"`
+	// MaxSessions caps the active sessions per user, 0 disables the cap.
+	MaxSessions int `env:"MAX_SESSIONS" yaml:"max_sessions" env-default:"10"`
+}
+
+// JWTConfig describes the access token signing keys. The key set by Secret
+// (HMAC algorithms) or PrivateKeyPath (asymmetric ones) gets the KID ID.
+// More keys are loaded from KeysDir, see jwt.Keyring.LoadDir. ActiveKID
+// defaults to KID; keys listed in RetiredKIDs no longer verify tokens.
+type JWTConfig struct {
+	Alg            string   `env:"JWT_ALG" yaml:"alg" env-default:"HS512"`
+	Secret         string   `env:"JWT_SECRET"`
+	PrivateKeyPath string   `env:"JWT_PRIVATE_KEY_PATH" yaml:"private_key_path"`
+	KID            string   `env:"JWT_KID" yaml:"kid" env-default:"default"`
+	KeysDir        string   `env:"JWT_KEYS_DIR" yaml:"keys_dir"`
+	ActiveKID      string   `env:"JWT_ACTIVE_KID" yaml:"active_kid"`
+	RetiredKIDs    []string `env:"JWT_RETIRED_KIDS" yaml:"retired_kids" env-separator:","`
+
+	Issuer   string        `env:"JWT_ISSUER" yaml:"issuer"`
+	Audience string        `env:"JWT_AUDIENCE" yaml:"audience"`
+	Leeway   time.Duration `env:"JWT_LEEWAY" yaml:"leeway" env-default:"30s"`
+
+	// Access tokens are encrypted into a JWE when EncryptionAlg is set:
+	// "dir" uses the base64 encoded 32-byte EncryptionKey, "RSA-OAEP-256"
+	// uses the PEM private key at EncryptionKeyPath.
+	EncryptionAlg     string `env:"JWT_ENCRYPTION_ALG" yaml:"encryption_alg"`
+	EncryptionKey     string `env:"JWT_ENCRYPTION_KEY"`
+	EncryptionKeyPath string `env:"JWT_ENCRYPTION_KEY_PATH" yaml:"encryption_key_path"`
+}
+
+// RefreshHashConfig describes how refresh token secrets are hashed. Alg is
+// one of bcrypt, argon2id and scrypt; only the parameters of the chosen
+// algorithm are used. Argon2Memory is in KiB and ScryptN must be a power
+// of two.
+type RefreshHashConfig struct {
+	Alg string `env:"REFRESH_HASH_ALG" yaml:"alg" env-default:"bcrypt"`
+
+	BcryptCost int `env:"REFRESH_HASH_BCRYPT_COST" yaml:"bcrypt_cost" env-default:"10"`
+
+	Argon2Time    uint32 `env:"REFRESH_HASH_ARGON2_TIME" yaml:"argon2_time" env-default:"1"`
+	Argon2Memory  uint32 `env:"REFRESH_HASH_ARGON2_MEMORY" yaml:"argon2_memory" env-default:"19456"`
+	Argon2Threads uint8  `env:"REFRESH_HASH_ARGON2_THREADS" yaml:"argon2_threads" env-default:"1"`
+
+	ScryptN int `env:"REFRESH_HASH_SCRYPT_N" yaml:"scrypt_n" env-default:"32768"`
+	ScryptR int `env:"REFRESH_HASH_SCRYPT_R" yaml:"scrypt_r" env-default:"8"`
+	ScryptP int `env:"REFRESH_HASH_SCRYPT_P" yaml:"scrypt_p" env-default:"1"`
 }
 
 type HTTPConfig struct {
@@ -27,6 +83,17 @@ type HTTPConfig struct {
 	ReadTimeout  time.Duration `env:"READ_TIMEOUT" yaml:"read_timeout" env-default:"10"`
 }
 
+type WebhookConfig struct {
+	URL          string        `env:"WEBHOOK_URL" yaml:"url"`
+	Secret       string        `env:"WEBHOOK_SECRET"`
+	Timeout      time.Duration `env:"WEBHOOK_TIMEOUT" yaml:"timeout" env-default:"5s"`
+	PollInterval time.Duration `env:"WEBHOOK_POLL_INTERVAL" yaml:"poll_interval" env-default:"2s"`
+	BatchSize    int           `env:"WEBHOOK_BATCH_SIZE" yaml:"batch_size" env-default:"20"`
+	MaxAttempts  int           `env:"WEBHOOK_MAX_ATTEMPTS" yaml:"max_attempts" env-default:"8"`
+	BaseBackoff  time.Duration `env:"WEBHOOK_BASE_BACKOFF" yaml:"base_backoff" env-default:"5s"`
+	MaxBackoff   time.Duration `env:"WEBHOOK_MAX_BACKOFF" yaml:"max_backoff" env-default:"30m"`
+}
+
 type PostgresConfig struct {
 	Host     string `env:"POSTGRES_HOST" yaml:"host" env-required:"true"`
 	Port     int    `env:"POSTGRES_PORT" yaml:"port" env-required:"true"`
@@ -37,7 +104,8 @@ type PostgresConfig struct {
 }
 
 func (p PostgresConfig) DSN() string {
-	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", p.Username, p.Password, p.Host, p.Port, p.Database)}
+	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", p.Username, p.Password, p.Host, p.Port, p.Database)
+}
 
 // MustLoad loads the configuration from a file specified by the `config` flag or
 // the `CONFIG_PATH` environment variable. If the configuration file is not found
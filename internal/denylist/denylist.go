@@ -0,0 +1,264 @@
+package denylist
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/passwordhash/jwt-test-task/internal/domain/models"
+	"github.com/passwordhash/jwt-test-task/pkg/clock"
+)
+
+const (
+	// pruneInterval is how often expired entries are dropped from memory
+	// and deleted from the storage.
+	pruneInterval = time.Minute
+
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+type Storage interface {
+	Add(ctx context.Context, tokenIDs []string, ttl time.Duration) error
+	Active(ctx context.Context) ([]models.RevokedAccessToken, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+	Listen(ctx context.Context, ready func() error, fn func(models.RevokedAccessToken)) error
+}
+
+// Denylist is the in-memory set of revoked access token IDs shared by all
+// instances. It is loaded from the storage on start and kept up to date
+// through storage notifications, so a token revoked by one instance is
+// rejected by the others without a query per request. After a lost
+// connection the set is reloaded, so no revocation is missed.
+type Denylist struct {
+	log     *slog.Logger
+	storage Storage
+	ttl     time.Duration
+	clock   clock.Clock
+
+	mu      sync.RWMutex
+	entries map[string]time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type Option func(*Denylist)
+
+// WithClock sets the clock the entries expire by. It defaults to the system
+// clock.
+func WithClock(c clock.Clock) Option {
+	return func(d *Denylist) {
+		d.clock = c
+	}
+}
+
+// New creates a Denylist. ttl is how long an entry is kept; it must cover
+// the lifetime of the access tokens including the allowed clock skew.
+func New(log *slog.Logger, storage Storage, ttl time.Duration, opts ...Option) *Denylist {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	d := &Denylist{
+		log:     log,
+		storage: storage,
+		ttl:     ttl,
+		clock:   clock.System{},
+		entries: make(map[string]time.Time),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// Start loads the denylist and keeps it up to date in the background.
+func (d *Denylist) Start(ctx context.Context) error {
+	const op = "denylist.Denylist.Start"
+
+	d.log.Info("Starting access token denylist", slog.String("op", op))
+
+	if err := d.load(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	d.wg.Add(2)
+	go d.listen()
+	go d.prune()
+
+	return nil
+}
+
+// Stop stops updating the denylist. It returns when the background work is
+// done or ctx is done.
+func (d *Denylist) Stop(ctx context.Context) {
+	const op = "denylist.Denylist.Stop"
+
+	log := d.log.With(slog.String("op", op))
+
+	log.Info("Stopping access token denylist")
+
+	d.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Info("Access token denylist stopped gracefully")
+	case <-ctx.Done():
+		log.Warn("Access token denylist stop timed out")
+	}
+}
+
+// Contains reports whether the access token ID is denylisted.
+func (d *Denylist) Contains(tokenID string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	expiresAt, ok := d.entries[tokenID]
+
+	return ok && d.clock.Now().Before(expiresAt)
+}
+
+// Add denylists the access token IDs on every instance. They are rejected
+// by this instance right away, even if the storage fails.
+func (d *Denylist) Add(ctx context.Context, tokenIDs []string) error {
+	const op = "denylist.Denylist.Add"
+
+	if len(tokenIDs) == 0 {
+		return nil
+	}
+
+	expiresAt := d.clock.Now().Add(d.ttl)
+
+	d.mu.Lock()
+	for _, id := range tokenIDs {
+		d.entries[id] = expiresAt
+	}
+	d.mu.Unlock()
+
+	if err := d.storage.Add(ctx, tokenIDs, d.ttl); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (d *Denylist) load(ctx context.Context) error {
+	tokens, err := d.storage.Active(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := d.clock.Now()
+	entries := make(map[string]time.Time, len(tokens))
+	for _, t := range tokens {
+		entries[t.TokenID] = now.Add(t.ExpiresIn)
+	}
+
+	d.mu.Lock()
+	// Entries added locally while loading must not be lost.
+	for id, expiresAt := range d.entries {
+		if _, ok := entries[id]; !ok && now.Before(expiresAt) {
+			entries[id] = expiresAt
+		}
+	}
+	d.entries = entries
+	d.mu.Unlock()
+
+	d.log.Info("access token denylist loaded", slog.Int("count", len(entries)))
+
+	return nil
+}
+
+func (d *Denylist) set(t models.RevokedAccessToken) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.entries[t.TokenID] = d.clock.Now().Add(t.ExpiresIn)
+}
+
+// listen applies the storage notifications. The set is reloaded every time
+// the subscription is (re)established to catch up on missed entries.
+func (d *Denylist) listen() {
+	const op = "denylist.Denylist.listen"
+
+	defer d.wg.Done()
+
+	log := d.log.With(slog.String("op", op))
+	delay := minReconnectDelay
+
+	for {
+		err := d.storage.Listen(d.ctx, func() error {
+			delay = minReconnectDelay
+
+			return d.load(d.ctx)
+		}, d.set)
+		if d.ctx.Err() != nil {
+			return
+		}
+
+		log.Error("denylist subscription lost, reconnecting",
+			slog.Duration("retryIn", delay),
+			slog.Any("error", err),
+		)
+
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+func (d *Denylist) prune() {
+	const op = "denylist.Denylist.prune"
+
+	defer d.wg.Done()
+
+	log := d.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		d.dropExpired()
+
+		// Every instance deletes; it is idempotent and cheap with the index.
+		if _, err := d.storage.DeleteExpired(d.ctx); err != nil && d.ctx.Err() == nil {
+			log.Error("failed to delete expired denylist entries", slog.Any("error", err))
+		}
+	}
+}
+
+// dropExpired removes the expired entries from memory.
+func (d *Denylist) dropExpired() {
+	now := d.clock.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for id, expiresAt := range d.entries {
+		if !now.Before(expiresAt) {
+			delete(d.entries, id)
+		}
+	}
+}
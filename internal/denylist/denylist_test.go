@@ -0,0 +1,273 @@
+package denylist
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/passwordhash/jwt-test-task/internal/domain/models"
+	"github.com/passwordhash/jwt-test-task/pkg/clock"
+)
+
+const testTTL = time.Hour
+
+var testNow = time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeStorage serves a fixed set of entries and delivers the notifications
+// sent to it once a subscription is established.
+type fakeStorage struct {
+	mu      sync.Mutex
+	active  []models.RevokedAccessToken
+	added   []string
+	addErr  error
+	loads   int
+	listens int
+	// failListens is the number of subscriptions that fail before one is
+	// established.
+	failListens int
+
+	notifications chan models.RevokedAccessToken
+}
+
+func newFakeStorage(active ...models.RevokedAccessToken) *fakeStorage {
+	return &fakeStorage{
+		active:        active,
+		notifications: make(chan models.RevokedAccessToken),
+	}
+}
+
+func (f *fakeStorage) Add(_ context.Context, tokenIDs []string, _ time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.added = append(f.added, tokenIDs...)
+
+	return f.addErr
+}
+
+func (f *fakeStorage) Active(context.Context) ([]models.RevokedAccessToken, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.loads++
+
+	return f.active, nil
+}
+
+func (f *fakeStorage) DeleteExpired(context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeStorage) Listen(
+	ctx context.Context,
+	ready func() error,
+	fn func(models.RevokedAccessToken),
+) error {
+	f.mu.Lock()
+	f.listens++
+	fail := f.failListens > 0
+	if fail {
+		f.failListens--
+	}
+	f.mu.Unlock()
+
+	if fail {
+		return errors.New("connection refused")
+	}
+
+	if err := ready(); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case t := <-f.notifications:
+			fn(t)
+		}
+	}
+}
+
+func (f *fakeStorage) counts() (loads, listens int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.loads, f.listens
+}
+
+func newTestDenylist(storage Storage, c clock.Clock) *Denylist {
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), storage, testTTL, WithClock(c))
+}
+
+func TestLoad(t *testing.T) {
+	c := clock.NewFake(testNow)
+	d := newTestDenylist(newFakeStorage(
+		models.RevokedAccessToken{TokenID: "stored", ExpiresIn: time.Minute},
+		models.RevokedAccessToken{TokenID: "expired", ExpiresIn: 0},
+	), c)
+
+	// Entries added locally before the load are kept, unless expired.
+	d.entries["local"] = testNow.Add(time.Minute)
+	d.entries["local-expired"] = testNow
+
+	if err := d.load(context.Background()); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+
+	tests := []struct {
+		tokenID string
+		after   time.Duration
+		want    bool
+	}{
+		{tokenID: "stored", after: 0, want: true},
+		{tokenID: "stored", after: time.Minute - time.Second, want: true},
+		{tokenID: "stored", after: time.Minute, want: false},
+		{tokenID: "expired", after: 0, want: false},
+		{tokenID: "local", after: 0, want: true},
+		{tokenID: "local-expired", after: 0, want: false},
+		{tokenID: "unknown", after: 0, want: false},
+	}
+
+	for _, tt := range tests {
+		c.Set(testNow.Add(tt.after))
+
+		if got := d.Contains(tt.tokenID); got != tt.want {
+			t.Errorf("Contains(%q) after %s = %v, want %v", tt.tokenID, tt.after, got, tt.want)
+		}
+	}
+
+	if _, ok := d.entries["local-expired"]; ok {
+		t.Error("load() kept an expired local entry")
+	}
+}
+
+func TestSet(t *testing.T) {
+	c := clock.NewFake(testNow)
+	d := newTestDenylist(newFakeStorage(), c)
+
+	d.set(models.RevokedAccessToken{TokenID: "notified", ExpiresIn: 30 * time.Second})
+
+	c.Advance(29 * time.Second)
+	if !d.Contains("notified") {
+		t.Error("Contains() = false before the entry expired")
+	}
+
+	c.Advance(time.Second)
+	if d.Contains("notified") {
+		t.Error("Contains() = true once the entry expired")
+	}
+}
+
+func TestDropExpired(t *testing.T) {
+	c := clock.NewFake(testNow)
+	d := newTestDenylist(newFakeStorage(), c)
+
+	d.set(models.RevokedAccessToken{TokenID: "short", ExpiresIn: time.Minute})
+	d.set(models.RevokedAccessToken{TokenID: "long", ExpiresIn: time.Hour})
+
+	c.Advance(time.Minute)
+	d.dropExpired()
+
+	if _, ok := d.entries["short"]; ok {
+		t.Error("dropExpired() kept an expired entry")
+	}
+	if _, ok := d.entries["long"]; !ok {
+		t.Error("dropExpired() dropped an active entry")
+	}
+}
+
+func TestAdd(t *testing.T) {
+	c := clock.NewFake(testNow)
+	storage := newFakeStorage()
+	storage.addErr = errors.New("storage is down")
+	d := newTestDenylist(storage, c)
+
+	err := d.Add(context.Background(), []string{"a", "b"})
+	if !errors.Is(err, storage.addErr) {
+		t.Errorf("Add() error = %v, want %v", err, storage.addErr)
+	}
+
+	// The entries are rejected locally even though the storage failed.
+	for _, id := range []string{"a", "b"} {
+		if !d.Contains(id) {
+			t.Errorf("Contains(%q) = false after Add()", id)
+		}
+	}
+
+	c.Advance(testTTL)
+	if d.Contains("a") {
+		t.Error("Contains() = true after the ttl")
+	}
+
+	if len(storage.added) != 2 {
+		t.Errorf("storage got %v, want both entries", storage.added)
+	}
+}
+
+func TestStartAppliesNotifications(t *testing.T) {
+	c := clock.NewFake(testNow)
+	storage := newFakeStorage(models.RevokedAccessToken{TokenID: "stored", ExpiresIn: time.Minute})
+	d := newTestDenylist(storage, c)
+
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer d.Stop(context.Background())
+
+	if !d.Contains("stored") {
+		t.Error("Contains() = false for a stored entry after Start()")
+	}
+
+	// The send blocks until the subscription is up and picks it up.
+	storage.notifications <- models.RevokedAccessToken{TokenID: "notified", ExpiresIn: time.Minute}
+
+	waitFor(t, func() bool { return d.Contains("notified") })
+}
+
+func TestListenReconnects(t *testing.T) {
+	c := clock.NewFake(testNow)
+	storage := newFakeStorage()
+	storage.failListens = 1
+	d := newTestDenylist(storage, c)
+
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	// Revoked while the subscription was down.
+	storage.mu.Lock()
+	storage.active = []models.RevokedAccessToken{{TokenID: "missed", ExpiresIn: time.Minute}}
+	storage.mu.Unlock()
+
+	// The reconnect reloads the set, so the missed entry shows up.
+	waitFor(t, func() bool { return d.Contains("missed") })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	d.Stop(ctx)
+
+	loads, listens := storage.counts()
+	if listens != 2 {
+		t.Errorf("Listen() called %d times, want 2", listens)
+	}
+	if loads != 2 {
+		t.Errorf("Active() called %d times, want 2, on start and on reconnect", loads)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
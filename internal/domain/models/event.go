@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+const (
+	EventTypeNewIP       = "refresh.new_ip"
+	EventTypeTokenReused = "refresh.token_reused"
+)
+
+// NewIPEvent describes a refresh attempt made from an IP address that
+// differs from the one the refresh token was issued to.
+type NewIPEvent struct {
+	UserID    string    `json:"user_id"`
+	OldIP     string    `json:"old_ip"`
+	NewIP     string    `json:"new_ip"`
+	UserAgent string    `json:"user_agent"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// TokenReusedEvent describes a refresh attempt with an already rotated
+// refresh token. Such a token was most likely stolen, so every session of
+// its family is revoked.
+type TokenReusedEvent struct {
+	UserID    string    `json:"user_id"`
+	FamilyID  string    `json:"family_id"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// OutboxEvent is a webhook event waiting for delivery. Payload holds the
+// JSON encoded event data.
+type OutboxEvent struct {
+	ID        string
+	Type      string
+	Payload   []byte
+	Attempts  int
+	CreatedAt time.Time
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// RefreshToken is a stored refresh token record. The token itself is never
+// stored, only its hash.
+//
+// Every rotation creates a new record in the family of the rotated one.
+// ParentID points to the rotated record; it is nil for the first token of
+// a session. Successor is the pair the record was rotated to, sealed with a
+// key derived from the rotated token, see auth.RefreshTokenGenerator.Seal.
+type RefreshToken struct {
+	ID        string
+	UserID    string
+	TokenID   string
+	TokenHash string
+	UserAgent string
+	IP        string
+	FamilyID  string
+	ParentID  *string
+	IsRevoked bool
+	UsedAt    *time.Time
+	Successor []byte
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// RevokedAccessToken is a denylisted access token ID. ExpiresIn is how long
+// the entry stays relevant: after that every token with the ID has expired.
+type RevokedAccessToken struct {
+	TokenID   string
+	ExpiresIn time.Duration
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Session is a login of the user as shown to its owner. ID is the token
+// family ID, which stays the same across refreshes. CreatedAt is when the
+// user logged in, LastUsedAt when the session was last refreshed; IP is
+// the address of that refresh. Current marks the session of the access
+// token the request was made with.
+type Session struct {
+	ID         string
+	UserAgent  string
+	IP         string
+	Current    bool
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+}
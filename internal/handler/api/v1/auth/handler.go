@@ -2,34 +2,54 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
+	"github.com/passwordhash/jwt-test-task/internal/domain/models"
 	"github.com/passwordhash/jwt-test-task/internal/handler/api/v1/middleware"
 	"github.com/passwordhash/jwt-test-task/internal/handler/api/v1/response"
+	svcErr "github.com/passwordhash/jwt-test-task/internal/service/errors"
 )
 
 type TokensProvider interface {
 	GetPair(ctx context.Context, id, remoteAddr, userAgent string) (access, refresh string, err error)
-	UserIDByToken(ctx context.Context, token string) (string, error)
+	Identify(ctx context.Context, token string) (userID, tokenID string, err error)
+}
+
+type TokensRefresher interface {
+	Refresh(ctx context.Context, accessToken, refreshToken, remoteAddr, userAgent string) (access, refresh string, err error)
 }
 
 type TokenRevoker interface {
-	RevokeRefreshToken(ctx context.Context, userID, userAgent string) error
+	RevokeRefreshToken(ctx context.Context, userID, tokenID string) error
+}
+
+type SessionManager interface {
+	Sessions(ctx context.Context, userID, currentTokenID string) ([]models.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	RevokeOtherSessions(ctx context.Context, userID, currentTokenID string) (int, error)
 }
 
 type Handler struct {
-	tokensProvider TokensProvider
-	tokenRevoker   TokenRevoker
+	tokensProvider  TokensProvider
+	tokensRefresher TokensRefresher
+	tokenRevoker    TokenRevoker
+	sessionManager  SessionManager
 }
 
 func New(
 	tokensProvider TokensProvider,
+	tokensRefresher TokensRefresher,
 	tokenRevoker TokenRevoker,
+	sessionManager SessionManager,
 ) *Handler {
 	return &Handler{
-		tokensProvider: tokensProvider,
-		tokenRevoker:   tokenRevoker,
+		tokensProvider:  tokensProvider,
+		tokensRefresher: tokensRefresher,
+		tokenRevoker:    tokenRevoker,
+		sessionManager:  sessionManager,
 	}
 }
 
@@ -68,7 +88,51 @@ func (h *Handler) refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, _ = fmt.Fprintf(w, "refresh endpoint")
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	if req.AccessToken == "" || req.RefreshToken == "" {
+		response.BadRequest(w, "access_token and refresh_token are required")
+		return
+	}
+
+	userAgent := r.Header.Get("User-Agent")
+	if userAgent == "" {
+		response.BadRequest(w, "User-Agent is required")
+		return
+	}
+
+	access, refresh, err := h.tokensRefresher.Refresh(
+		r.Context(),
+		req.AccessToken,
+		req.RefreshToken,
+		r.RemoteAddr,
+		userAgent,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, svcErr.ErrInvalidAccessToken),
+			errors.Is(err, svcErr.ErrInvalidRefreshToken),
+			errors.Is(err, svcErr.ErrTokenPairMismatch),
+			errors.Is(err, svcErr.ErrRefreshTokenRevoked),
+			errors.Is(err, svcErr.ErrRefreshTokenUsed),
+			errors.Is(err, svcErr.ErrRefreshTokenReused):
+			response.Unauthorized(w, err.Error())
+		case errors.Is(err, svcErr.ErrUserAgentMismatch):
+			response.Forbidden(w, err.Error())
+		default:
+			response.InternalError(w, "failed to refresh tokens")
+		}
+		return
+	}
+
+	response.OK(w, tokensResponse{
+		AccessToken:  access,
+		RefreshToken: refresh,
+	})
 }
 
 func (h *Handler) identify(w http.ResponseWriter, r *http.Request) {
@@ -98,7 +162,9 @@ func (h *Handler) logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.tokenRevoker.RevokeRefreshToken(r.Context(), userID, r.Header.Get("User-Agent"))
+	tokenID, _ := r.Context().Value(middleware.TokenIDKey).(string)
+
+	err := h.tokenRevoker.RevokeRefreshToken(r.Context(), userID, tokenID)
 	if err != nil {
 		// TODO: add proper handling
 		http.Error(w, fmt.Sprintf("failed to revoke tokens: %v", err), http.StatusInternalServerError)
@@ -107,3 +173,94 @@ func (h *Handler) logout(w http.ResponseWriter, r *http.Request) {
 
 	response.OK(w, "Token revoked successfully")
 }
+
+func (h *Handler) sessions(w http.ResponseWriter, r *http.Request) {
+	if !response.ValidateMethod(r, w, http.MethodGet) {
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		response.Unauthorized(w, "Unable to identify user")
+		return
+	}
+
+	tokenID, _ := r.Context().Value(middleware.TokenIDKey).(string)
+
+	sessions, err := h.sessionManager.Sessions(r.Context(), userID, tokenID)
+	if err != nil {
+		response.InternalError(w, "failed to get sessions")
+		return
+	}
+
+	resp := sessionsResponse{Sessions: make([]sessionResponse, 0, len(sessions))}
+	for _, s := range sessions {
+		resp.Sessions = append(resp.Sessions, sessionResponse{
+			ID:         s.ID,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			Current:    s.Current,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+		})
+	}
+
+	response.OK(w, resp)
+}
+
+func (h *Handler) revokeSession(w http.ResponseWriter, r *http.Request) {
+	if !response.ValidateMethod(r, w, http.MethodDelete) {
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		response.Unauthorized(w, "Unable to identify user")
+		return
+	}
+
+	err := h.sessionManager.RevokeSession(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, svcErr.ErrInvalidID):
+			response.BadRequest(w, err.Error())
+		case errors.Is(err, svcErr.ErrSessionNotFound):
+			response.NotFound(w, err.Error())
+		default:
+			response.InternalError(w, "failed to revoke session")
+		}
+		return
+	}
+
+	response.OK(w, "Session revoked successfully")
+}
+
+func (h *Handler) revokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	if !response.ValidateMethod(r, w, http.MethodPost) {
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		response.Unauthorized(w, "Unable to identify user")
+		return
+	}
+
+	tokenID, _ := r.Context().Value(middleware.TokenIDKey).(string)
+
+	revoked, err := h.sessionManager.RevokeOtherSessions(r.Context(), userID, tokenID)
+	if err != nil {
+		switch {
+		case errors.Is(err, svcErr.ErrInvalidID),
+			errors.Is(err, svcErr.ErrInvalidAccessToken):
+			response.Unauthorized(w, err.Error())
+		default:
+			response.InternalError(w, "failed to revoke sessions")
+		}
+		return
+	}
+
+	response.OK(w, revokedSessionsResponse{
+		Revoked: revoked,
+	})
+}
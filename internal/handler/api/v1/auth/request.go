@@ -0,0 +1,6 @@
+package auth
+
+type refreshRequest struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
@@ -1,5 +1,7 @@
 package auth
 
+import "time"
+
 type tokensResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
@@ -8,3 +10,20 @@ type tokensResponse struct {
 type userIDResponse struct {
 	UserID string `json:"user_id"`
 }
+
+type sessionResponse struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	Current    bool      `json:"current"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+type sessionsResponse struct {
+	Sessions []sessionResponse `json:"sessions"`
+}
+
+type revokedSessionsResponse struct {
+	Revoked int `json:"revoked"`
+}
@@ -11,4 +11,8 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/auth/refresh", h.refresh)
 	mux.Handle("/api/v1/auth/me", middleware.Identity(http.HandlerFunc(h.identify), h.tokensProvider))
 	mux.Handle("/api/v1/auth/logout", middleware.Identity(http.HandlerFunc(h.logout), h.tokensProvider))
+	mux.Handle("/api/v1/auth/sessions", middleware.Identity(http.HandlerFunc(h.sessions), h.tokensProvider))
+	mux.Handle("/api/v1/auth/sessions/{id}", middleware.Identity(http.HandlerFunc(h.revokeSession), h.tokensProvider))
+	mux.Handle("/api/v1/auth/sessions/revoke-others",
+		middleware.Identity(http.HandlerFunc(h.revokeOtherSessions), h.tokensProvider))
 }
@@ -13,10 +13,13 @@ type CtxKey string
 
 const (
 	UserIDKey CtxKey = "userID"
+	// TokenIDKey holds the token ID of the access token, it identifies the
+	// session the request was made from.
+	TokenIDKey CtxKey = "tokenID"
 )
 
 type IdentityProvider interface {
-	UserIDByToken(ctx context.Context, token string) (string, error)
+	Identify(ctx context.Context, token string) (userID, tokenID string, err error)
 }
 
 func Identity(next http.Handler, provider IdentityProvider) http.Handler {
@@ -27,13 +30,14 @@ func Identity(next http.Handler, provider IdentityProvider) http.Handler {
 			return
 		}
 
-		userID, err := provider.UserIDByToken(r.Context(), jwtToken)
+		userID, tokenID, err := provider.Identify(r.Context(), jwtToken)
 		if err != nil || userID == "" {
 			response.Unauthorized(w, fmt.Sprintf("failed to get user ID by token: %v", err))
 			return
 		}
 
 		ctx := context.WithValue(r.Context(), UserIDKey, userID)
+		ctx = context.WithValue(ctx, TokenIDKey, tokenID)
 		r = r.WithContext(ctx)
 
 		next.ServeHTTP(w, r)
@@ -27,6 +27,38 @@ func Unauthorized(w http.ResponseWriter, message string) {
 	})
 }
 
+func Forbidden(w http.ResponseWriter, message string) {
+	jsonResponse(w, http.StatusForbidden, response{
+		Success: false,
+		Data:    nil,
+		Message: message,
+	})
+}
+
+func BadRequest(w http.ResponseWriter, message string) {
+	jsonResponse(w, http.StatusBadRequest, response{
+		Success: false,
+		Data:    nil,
+		Message: message,
+	})
+}
+
+func NotFound(w http.ResponseWriter, message string) {
+	jsonResponse(w, http.StatusNotFound, response{
+		Success: false,
+		Data:    nil,
+		Message: message,
+	})
+}
+
+func InternalError(w http.ResponseWriter, message string) {
+	jsonResponse(w, http.StatusInternalServerError, response{
+		Success: false,
+		Data:    nil,
+		Message: message,
+	})
+}
+
 func jsonResponse(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -0,0 +1,58 @@
+package wellknown
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/passwordhash/jwt-test-task/internal/handler/api/v1/response"
+	"github.com/passwordhash/jwt-test-task/pkg/jwt"
+)
+
+// jwksMaxAge is how long clients may cache the key set. Keys are added to
+// the keyring before they become active, so a client that refetches within
+// this time doesn't miss them.
+const jwksMaxAge = "max-age=300"
+
+type JWKSProvider interface {
+	JWKS() jwt.JWKS
+}
+
+type Handler struct {
+	jwksProvider JWKSProvider
+}
+
+func New(jwksProvider JWKSProvider) *Handler {
+	return &Handler{
+		jwksProvider: jwksProvider,
+	}
+}
+
+// jwks serves the public access token keys as an RFC 7517 key set. The
+// response carries an ETag, so clients can revalidate with If-None-Match.
+func (h *Handler) jwks(w http.ResponseWriter, r *http.Request) {
+	if !response.ValidateMethod(r, w, http.MethodGet) {
+		return
+	}
+
+	body, err := json.Marshal(h.jwksProvider.JWKS())
+	if err != nil {
+		response.InternalError(w, "failed to encode key set")
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("Cache-Control", "public, "+jwksMaxAge)
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	_, _ = w.Write(body)
+}
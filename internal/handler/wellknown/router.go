@@ -0,0 +1,9 @@
+package wellknown
+
+import (
+	"net/http"
+)
+
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/.well-known/jwks.json", h.jwks)
+}
@@ -2,6 +2,8 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
@@ -9,60 +11,140 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/passwordhash/jwt-test-task/internal/domain/models"
 	svcErr "github.com/passwordhash/jwt-test-task/internal/service/errors"
+	repoErr "github.com/passwordhash/jwt-test-task/internal/storage/errors"
+	"github.com/passwordhash/jwt-test-task/pkg/clock"
 	"github.com/passwordhash/jwt-test-task/pkg/jwt"
 )
 
-const (
-	refreshTokenLength = 32
-)
-
-const (
-	claimTokenID = "token_id"
-)
+// accessClaims are the access token claims. TokenID binds the access token
+// to the refresh token issued with it.
+type accessClaims struct {
+	jwt.RegisteredClaims
+	TokenID string `json:"token_id"`
+}
 
 type RefreshTokenSaver interface {
-	Save(ctx context.Context, userID, tokenID, tokenHash, userAgent, ip string) (string, error)
+	Save(ctx context.Context, id, userID, tokenID, tokenHash, userAgent, ip string, maxSessions int) ([]string, error)
+}
+
+type RefreshTokenProvider interface {
+	ByID(ctx context.Context, id string) (models.RefreshToken, error)
+	ByTokenID(ctx context.Context, tokenID string) (models.RefreshToken, error)
+	SessionsByUserID(ctx context.Context, userID string) ([]models.Session, error)
+}
+
+type RefreshTokenRotator interface {
+	Rotate(
+		ctx context.Context,
+		id, newID, tokenID, tokenHash, ip string,
+		successor []byte,
+		events ...models.OutboxEvent,
+	) error
 }
 
 type RefreshTokenRevoker interface {
-	Revoke(ctx context.Context, userID, userAgent string) error
+	RevokeByTokenID(ctx context.Context, userID, tokenID string) ([]string, error)
+	RevokeAll(ctx context.Context, userID string) ([]string, error)
+	RevokeFamily(ctx context.Context, familyID string, events ...models.OutboxEvent) ([]string, error)
+	RevokeSession(ctx context.Context, userID, familyID string) ([]string, error)
+	RevokeOthers(ctx context.Context, userID, keepTokenID string) ([]string, int, error)
 }
 
+// RefreshTokenGenerator issues and checks refresh tokens. Hash always uses
+// the current hashing parameters and Compare accepts hashes made with any
+// of them. Every refresh rotates the token, so a hash with outdated
+// parameters is replaced by the successor's on its next successful refresh
+// and needs no separate upgrade.
 type RefreshTokenGenerator interface {
-	Generate(length int) (string, error)
-	Hash(token string) (string, error)
+	Generate(sessionID string) (token, secret string, err error)
+	Parse(token string) (sessionID, secret string, err error)
+	Hash(secret string) (string, error)
+	Compare(secret, hash string) error
+	// Seal and Open encrypt the successor pair of a rotated token with a
+	// key only the holder of the rotated token can derive.
+	Seal(secret string, data []byte) ([]byte, error)
+	Open(secret string, sealed []byte) ([]byte, error)
+}
+
+// Denylist is the set of revoked access token IDs shared by all instances
+// of the service.
+type Denylist interface {
+	Contains(tokenID string) bool
+	Add(ctx context.Context, tokenIDs []string) error
+}
+
+// KeyProvider holds the access token keys: the key that signs new tokens
+// and the keys that verify them by the kid header.
+type KeyProvider interface {
+	SigningKey() (jwt.SigningKey, error)
+	JWKS() jwt.JWKS
+	jwt.KeySet
 }
 
 type Service struct {
 	log *slog.Logger
 
 	refreshSaver          RefreshTokenSaver
+	refreshProvider       RefreshTokenProvider
+	refreshRotator        RefreshTokenRotator
 	refreshTokenGenerator RefreshTokenGenerator
 	refreshTokenRevoker   RefreshTokenRevoker
 
+	newIPEvents bool
+	reuseEvents bool
+	revocations *revocationCache
+	denylist    Denylist
+	maxSessions int
+
+	refreshGracePeriod time.Duration
+	rotations          *rotationCache
+
 	accessTTL time.Duration
-	secret    string
+	keys      KeyProvider
+	issuer    string
+	audience  string
+	leeway    time.Duration
+	clock     clock.Clock
+
+	encryptionKey jwt.EncryptionKey
+	decryptionKey jwt.DecryptionKey
 }
 
 func New(
 	log *slog.Logger,
 
 	refreshSaver RefreshTokenSaver,
+	refreshProvider RefreshTokenProvider,
+	refreshRotator RefreshTokenRotator,
 	refreshTokenGenerator RefreshTokenGenerator,
 	refreshTokenRevoker RefreshTokenRevoker,
 
 	accessTTL time.Duration,
-	secret string,
+	keys KeyProvider,
+
+	opts ...Option,
 ) *Service {
-	return &Service{
+	s := &Service{
 		log:                   log,
 		refreshSaver:          refreshSaver,
+		refreshProvider:       refreshProvider,
+		refreshRotator:        refreshRotator,
 		refreshTokenGenerator: refreshTokenGenerator,
 		refreshTokenRevoker:   refreshTokenRevoker,
 		accessTTL:             accessTTL,
-		secret:                secret,
+		keys:                  keys,
+		revocations:           newRevocationCache(defaultRevocationCacheTTL),
+		rotations:             newRotationCache(0),
+		clock:                 clock.System{},
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
 func (s *Service) GetPair(
@@ -86,27 +168,23 @@ func (s *Service) GetPair(
 		return "", "", svcErr.ErrInvalidID
 	}
 
-	tokenID := uuid.NewString()
-	claims := map[string]any{
-		"sub":        userID,
-		claimTokenID: tokenID,
-	}
-
-	access, err = jwt.NewToken("HS512", claims, s.accessTTL, s.secret)
+	tokenID, access, err := s.newAccessToken(userID)
 	if err != nil {
 		log.Error("failed to create access token", slog.Any("error", err))
 
 		return "", "", err
 	}
 
-	refreshHash, err := s.refreshTokenGenerator.Generate(refreshTokenLength)
+	sessionID := uuid.NewString()
+
+	refresh, refreshHash, err := s.newRefreshToken(sessionID)
 	if err != nil {
 		log.Error("failed to generate refresh token", slog.Any("error", err))
 
 		return "", "", err
 	}
 
-	_, err = s.refreshSaver.Save(ctx, userID, tokenID, refreshHash, userAgent, ip)
+	evicted, err := s.refreshSaver.Save(ctx, sessionID, userID, tokenID, refreshHash, userAgent, ip, s.maxSessions)
 	// TODO: handle error properly
 	if err != nil {
 		log.Error("failed to save refresh token", slog.Any("error", err))
@@ -114,34 +192,391 @@ func (s *Service) GetPair(
 		return "", "", err
 	}
 
+	if len(evicted) > 0 {
+		s.markRevoked(ctx, log, evicted)
+
+		log.Info("oldest sessions evicted", slog.Int("count", len(evicted)))
+	}
+
 	log.Info("tokens generated successfully")
 
 	return access, refresh, nil
 }
 
-func (s *Service) UserIDByToken(_ context.Context, token string) (string, error) {
-	const op = "tokens.service.UserIDByToken"
+// Refresh exchanges a token pair for a new one. The access token may be
+// expired, but it must carry a valid signature and belong to the same pair
+// as the refresh token. The used refresh token can't be exchanged again.
+func (s *Service) Refresh(
+	ctx context.Context,
+	accessToken, refreshToken, remoteAddr, userAgent string,
+) (access, refresh string, err error) {
+	const op = "tokens.service.Refresh"
+
+	log := s.log.With("op", op, "remoteAddr", remoteAddr, "userAgent", userAgent)
+
+	ip, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		log.Error("failed to split host and port from IP", slog.Any("error", err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	claims, err := s.parseAccessToken(accessToken, jwt.WithoutExpiration())
+	if err != nil {
+		log.Warn("failed to parse access token", slog.Any("error", err))
+
+		return "", "", svcErr.ErrInvalidAccessToken
+	}
+
+	userID, tokenID := claims.Subject, claims.TokenID
+	if userID == "" || tokenID == "" {
+		log.Warn("access token misses required claims")
+
+		return "", "", svcErr.ErrInvalidAccessToken
+	}
+
+	log = log.With("userID", userID, "tokenID", tokenID)
+
+	sessionID, secret, err := s.refreshTokenGenerator.Parse(refreshToken)
+	if err != nil {
+		log.Warn("malformed or tampered refresh token", slog.Any("error", err))
+
+		return "", "", svcErr.ErrInvalidRefreshToken
+	}
+
+	log = log.With("sessionID", sessionID)
+
+	stored, err := s.refreshProvider.ByID(ctx, sessionID)
+	if errors.Is(err, repoErr.ErrRefreshTokenNotFound) {
+		log.Warn("refresh token not found")
+
+		return "", "", svcErr.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		log.Error("failed to get refresh token", slog.Any("error", err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if stored.UserID != userID || stored.TokenID != tokenID {
+		log.Warn("refresh token was not issued with the access token",
+			slog.String("storedUserID", stored.UserID),
+			slog.String("storedTokenID", stored.TokenID),
+		)
+
+		return "", "", svcErr.ErrTokenPairMismatch
+	}
+
+	// The secret is compared first, so reuse is only detected for genuine
+	// tokens and a leaked access token alone can't revoke the family.
+	if err := s.refreshTokenGenerator.Compare(secret, stored.TokenHash); err != nil {
+		log.Warn("refresh token does not match the pair", slog.Any("error", err))
+
+		return "", "", svcErr.ErrTokenPairMismatch
+	}
+
+	// Revoking a session revokes its rotated tokens too, so the access tokens
+	// issued with them are rejected. A rotated token presented again is
+	// still reuse though, whether its family is revoked or not.
+	if stored.IsRevoked && stored.UsedAt == nil {
+		log.Warn("refresh token is revoked")
+
+		return "", "", svcErr.ErrRefreshTokenRevoked
+	}
+
+	if stored.UserAgent != userAgent {
+		log.Warn("security event: refresh attempt with changed user agent, revoking all user sessions",
+			slog.String("event", "refresh_user_agent_mismatch"),
+			slog.String("storedUserAgent", stored.UserAgent),
+			slog.String("ip", ip),
+		)
+
+		tokenIDs, err := s.refreshTokenRevoker.RevokeAll(ctx, userID)
+		if err != nil {
+			log.Error("failed to revoke user sessions", slog.Any("error", err))
+
+			return "", "", fmt.Errorf("%s: %w", op, err)
+		}
+
+		s.markRevoked(ctx, log, tokenIDs)
+
+		return "", "", svcErr.ErrUserAgentMismatch
+	}
+
+	now := s.clock.Now()
+
+	if stored.UsedAt != nil {
+		if r, ok := s.rotations.lookup(stored.ID, now); ok {
+			log.Info("refresh token was just rotated, returning the same pair")
+
+			return r.wait(ctx)
+		}
+
+		if s.refreshGracePeriod > 0 && now.Sub(*stored.UsedAt) <= s.refreshGracePeriod {
+			log.Info("refresh token was rotated within the grace period, returning the sealed pair")
+
+			return s.sealedPair(log, stored, secret)
+		}
+
+		return "", "", s.handleReuse(ctx, log, stored, ip, userAgent)
+	}
+
+	r, leader := s.rotations.begin(stored.ID, now)
+	if !leader {
+		log.Info("refresh token is being rotated concurrently, waiting for the pair")
+
+		return r.wait(ctx)
+	}
+
+	access, refresh, err = s.rotate(ctx, log, stored, secret, ip, userAgent)
+	s.rotations.finish(stored.ID, r, access, refresh, err, s.clock.Now())
+	if err != nil {
+		return "", "", err
+	}
+
+	log.Info("tokens refreshed successfully")
+
+	return access, refresh, nil
+}
+
+// successorPair is the pair a refresh token was rotated to, as sealed on
+// the rotated record.
+type successorPair struct {
+	Access  string `json:"access"`
+	Refresh string `json:"refresh"`
+}
+
+// rotate issues the successor pair of the stored refresh token and marks
+// the stored one as used. With a grace period, the pair is sealed with the
+// secret of the stored token and saved on its record, so the refreshes
+// that follow get the same pair on any instance.
+func (s *Service) rotate(
+	ctx context.Context,
+	log *slog.Logger,
+	stored models.RefreshToken,
+	secret, ip, userAgent string,
+) (access, refresh string, err error) {
+	const op = "tokens.service.rotate"
+
+	newTokenID, access, err := s.newAccessToken(stored.UserID)
+	if err != nil {
+		log.Error("failed to create access token", slog.Any("error", err))
+
+		return "", "", err
+	}
+
+	newID := uuid.NewString()
+
+	refresh, refreshHash, err := s.newRefreshToken(newID)
+	if err != nil {
+		log.Error("failed to generate refresh token", slog.Any("error", err))
+
+		return "", "", err
+	}
+
+	var events []models.OutboxEvent
+	if s.newIPEvents && stored.IP != ip {
+		event, err := newOutboxEvent(models.EventTypeNewIP, models.NewIPEvent{
+			UserID:    stored.UserID,
+			OldIP:     stored.IP,
+			NewIP:     ip,
+			UserAgent: userAgent,
+			Timestamp: s.clock.Now().UTC(),
+		})
+		if err != nil {
+			log.Error("failed to build new IP event", slog.Any("error", err))
+
+			return "", "", fmt.Errorf("%s: %w", op, err)
+		}
+
+		events = append(events, event)
+
+		log.Info("refresh from new IP, webhook event queued", slog.String("storedIP", stored.IP))
+	}
+
+	var successor []byte
+	if s.refreshGracePeriod > 0 {
+		successor, err = s.sealPair(secret, access, refresh)
+		if err != nil {
+			log.Error("failed to seal successor pair", slog.Any("error", err))
+
+			return "", "", fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	err = s.refreshRotator.Rotate(ctx, stored.ID, newID, newTokenID, refreshHash, ip, successor, events...)
+	if errors.Is(err, repoErr.ErrRefreshTokenNotFound) {
+		log.Warn("refresh token was deleted before it was rotated")
+
+		return "", "", svcErr.ErrInvalidRefreshToken
+	}
+	if errors.Is(err, repoErr.ErrRefreshTokenUsed) {
+		log.Warn("refresh token was rotated concurrently by another instance")
+
+		// The rotation that won has committed by now, its pair is sealed
+		// on the record.
+		rotated, err := s.refreshProvider.ByID(ctx, stored.ID)
+		if err != nil {
+			log.Error("failed to get rotated refresh token", slog.Any("error", err))
+
+			return "", "", svcErr.ErrRefreshTokenUsed
+		}
+
+		return s.sealedPair(log, rotated, secret)
+	}
+	if err != nil {
+		log.Error("failed to rotate refresh token", slog.Any("error", err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return access, refresh, nil
+}
+
+// sealPair seals the successor pair with the secret of the rotated token.
+func (s *Service) sealPair(secret, access, refresh string) ([]byte, error) {
+	data, err := json.Marshal(successorPair{Access: access, Refresh: refresh})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.refreshTokenGenerator.Seal(secret, data)
+}
+
+// sealedPair returns the pair the stored refresh token was rotated to while
+// the grace period lasts. Outside of it, or if the pair wasn't sealed, the
+// token is just used.
+func (s *Service) sealedPair(
+	log *slog.Logger,
+	stored models.RefreshToken,
+	secret string,
+) (access, refresh string, err error) {
+	if stored.IsRevoked || stored.UsedAt == nil || len(stored.Successor) == 0 ||
+		s.clock.Now().Sub(*stored.UsedAt) > s.refreshGracePeriod {
+		return "", "", svcErr.ErrRefreshTokenUsed
+	}
+
+	data, err := s.refreshTokenGenerator.Open(secret, stored.Successor)
+	if err != nil {
+		log.Error("failed to open sealed successor pair", slog.Any("error", err))
+
+		return "", "", svcErr.ErrRefreshTokenUsed
+	}
+
+	var pair successorPair
+	if err := json.Unmarshal(data, &pair); err != nil {
+		log.Error("failed to decode sealed successor pair", slog.Any("error", err))
+
+		return "", "", svcErr.ErrRefreshTokenUsed
+	}
+
+	return pair.Access, pair.Refresh, nil
+}
+
+// UserIDByToken returns the user ID from a valid access token. Tokens of
+// revoked sessions are rejected even if they are not expired yet.
+func (s *Service) UserIDByToken(ctx context.Context, token string) (string, error) {
+	userID, _, err := s.Identify(ctx, token)
+
+	return userID, err
+}
+
+// Identify returns the user ID and the token ID from a valid access token.
+// The token ID identifies the session the token was issued for. Tokens of
+// revoked sessions are rejected even if they are not expired yet.
+func (s *Service) Identify(ctx context.Context, token string) (userID, tokenID string, err error) {
+	const op = "tokens.service.Identify"
 
 	log := s.log.With("op", op, "token", token)
 
-	claims, err := jwt.ParseToken(token, s.secret)
+	claims, err := s.parseAccessToken(token)
 	if err != nil {
 		log.Error("failed to get user ID from token", slog.Any("error", err))
 
-		return "", fmt.Errorf("%s: %w", op, err)
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	userID = claims.Subject
+	if userID == "" {
+		log.Warn("access token has no subject")
+
+		return "", "", svcErr.ErrInvalidAccessToken
+	}
+
+	if s.denylist != nil && s.denylist.Contains(claims.TokenID) {
+		log.Warn("access token is denylisted", slog.String("tokenID", claims.TokenID))
+
+		return "", "", svcErr.ErrSessionRevoked
+	}
+
+	revoked, err := s.isSessionRevoked(ctx, claims.TokenID)
+	if err != nil {
+		log.Error("failed to check session revocation", slog.Any("error", err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+	if revoked {
+		log.Warn("access token of revoked session", slog.String("tokenID", claims.TokenID))
+
+		return "", "", svcErr.ErrSessionRevoked
 	}
 
-	userID := claims["sub"]
+	log.Info("user ID from token", slog.String("userID", userID))
+
+	return userID, claims.TokenID, nil
+}
+
+// markRevoked rejects the access tokens of revoked sessions right away on
+// this instance and, through the denylist, on the others. A denylist error
+// is only logged: the other instances still notice the revocation once
+// their revocation cache entries expire.
+func (s *Service) markRevoked(ctx context.Context, log *slog.Logger, tokenIDs []string) {
+	s.revocations.markRevoked(tokenIDs, s.clock.Now())
+
+	if s.denylist == nil {
+		return
+	}
+
+	if err := s.denylist.Add(ctx, tokenIDs); err != nil {
+		log.Error("failed to denylist revoked access tokens", slog.Any("error", err))
+	}
+}
+
+// isSessionRevoked reports whether the session the access token was issued
+// for is revoked. A token without a session is treated as revoked.
+func (s *Service) isSessionRevoked(ctx context.Context, tokenID string) (bool, error) {
+	if tokenID == "" {
+		return true, nil
+	}
+
+	now := s.clock.Now()
+
+	if revoked, ok := s.revocations.get(tokenID, now); ok {
+		return revoked, nil
+	}
+
+	stored, err := s.refreshProvider.ByTokenID(ctx, tokenID)
+	if errors.Is(err, repoErr.ErrRefreshTokenNotFound) {
+		s.revocations.set(tokenID, true, now)
+
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
 
-	log.Info("user ID from token", slog.Any("userID", userID))
+	s.revocations.set(tokenID, stored.IsRevoked, now)
 
-	return fmt.Sprintf("%v", userID), nil
+	return stored.IsRevoked, nil
 }
 
-func (s *Service) RevokeRefreshToken(ctx context.Context, userID, userAgent string) error {
+// RevokeRefreshToken revokes the session the access token tokenID was
+// issued for, including the access tokens issued before its last refresh.
+// Other sessions of the user, even from the same user agent, stay valid.
+func (s *Service) RevokeRefreshToken(ctx context.Context, userID, tokenID string) error {
 	const op = "tokens.service.RevokeRefreshToken"
 
-	log := s.log.With("op", op, "userID", userID, "userAgent", userAgent)
+	log := s.log.With("op", op, "userID", userID, "tokenID", tokenID)
 
 	if _, err := uuid.Parse(userID); err != nil {
 		log.Warn("invalid user ID", slog.String("userID", userID), slog.Any("error", err))
@@ -149,14 +584,173 @@ func (s *Service) RevokeRefreshToken(ctx context.Context, userID, userAgent stri
 		return svcErr.ErrInvalidID
 	}
 
-	err := s.refreshTokenRevoker.Revoke(ctx, userID, userAgent)
+	tokenIDs, err := s.refreshTokenRevoker.RevokeByTokenID(ctx, userID, tokenID)
 	if err != nil {
 		log.Error("failed to revoke refresh token", slog.Any("error", err))
 
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	s.markRevoked(ctx, log, tokenIDs)
+
 	log.Info("refresh token revoked successfully")
 
 	return nil
 }
+
+// JWKS returns the public keys that verify access tokens. HMAC keys are
+// never published.
+func (s *Service) JWKS() jwt.JWKS {
+	return s.keys.JWKS()
+}
+
+// handleReuse handles a refresh attempt with an already rotated token. The
+// token was presented twice, so either the legitimate client or an attacker
+// holds a copy: the whole family is revoked and a security event is raised.
+func (s *Service) handleReuse(
+	ctx context.Context,
+	log *slog.Logger,
+	stored models.RefreshToken,
+	ip, userAgent string,
+) error {
+	const op = "tokens.service.handleReuse"
+
+	log.Warn("security event: rotated refresh token reused, revoking token family",
+		slog.String("event", "refresh_token_reused"),
+		slog.String("familyID", stored.FamilyID),
+		slog.String("ip", ip),
+		slog.Time("usedAt", *stored.UsedAt),
+	)
+
+	var events []models.OutboxEvent
+	if s.reuseEvents {
+		event, err := newOutboxEvent(models.EventTypeTokenReused, models.TokenReusedEvent{
+			UserID:    stored.UserID,
+			FamilyID:  stored.FamilyID,
+			IP:        ip,
+			UserAgent: userAgent,
+			Timestamp: s.clock.Now().UTC(),
+		})
+		if err != nil {
+			log.Error("failed to build token reuse event", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		events = append(events, event)
+	}
+
+	tokenIDs, err := s.refreshTokenRevoker.RevokeFamily(ctx, stored.FamilyID, events...)
+	if err != nil {
+		log.Error("failed to revoke token family", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.markRevoked(ctx, log, tokenIDs)
+
+	log.Info("token family revoked", slog.Int("count", len(tokenIDs)))
+
+	return svcErr.ErrRefreshTokenReused
+}
+
+// newOutboxEvent wraps the event data into an outbox record.
+func newOutboxEvent(eventType string, data any) (models.OutboxEvent, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return models.OutboxEvent{}, err
+	}
+
+	return models.OutboxEvent{
+		Type:    eventType,
+		Payload: payload,
+	}, nil
+}
+
+// newAccessToken creates a signed access token for the user. The returned
+// token ID binds the access token to the refresh token issued with it.
+func (s *Service) newAccessToken(userID string) (tokenID, token string, err error) {
+	tokenID = uuid.NewString()
+	now := s.clock.Now()
+
+	claims := accessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.issuer,
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessTTL)),
+		},
+		TokenID: tokenID,
+	}
+	if s.audience != "" {
+		claims.Audience = jwt.Audience{s.audience}
+	}
+
+	key, err := s.keys.SigningKey()
+	if err != nil {
+		return "", "", err
+	}
+
+	token, err = jwt.NewTokenFrom(claims, key)
+	if err != nil {
+		return "", "", err
+	}
+
+	if s.encryptionKey != nil {
+		token, err = jwt.EncryptToken(token, s.encryptionKey)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	return tokenID, token, nil
+}
+
+// parseAccessToken verifies the access token and returns its claims.
+// Encrypted tokens are decrypted first.
+func (s *Service) parseAccessToken(token string, extra ...jwt.ParseOption) (accessClaims, error) {
+	if jwt.IsEncrypted(token) {
+		if s.decryptionKey == nil {
+			return accessClaims{}, svcErr.ErrInvalidAccessToken
+		}
+
+		signed, err := jwt.DecryptToken(token, s.decryptionKey)
+		if err != nil {
+			return accessClaims{}, err
+		}
+
+		token = signed
+	}
+
+	return jwt.ParseInto[accessClaims](s.parser(extra...), token, s.keys)
+}
+
+// parser returns the access token parser. The issuer and audience are
+// validated when they are configured.
+func (s *Service) parser(extra ...jwt.ParseOption) *jwt.Parser {
+	opts := []jwt.ParseOption{jwt.WithLeeway(s.leeway), jwt.WithClock(s.clock)}
+	if s.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(s.issuer))
+	}
+	if s.audience != "" {
+		opts = append(opts, jwt.WithAudience(s.audience))
+	}
+
+	return jwt.NewParser(append(opts, extra...)...)
+}
+
+// newRefreshToken generates a refresh token for the session and the hash of
+// its secret to be stored.
+func (s *Service) newRefreshToken(sessionID string) (token, hash string, err error) {
+	token, secret, err := s.refreshTokenGenerator.Generate(sessionID)
+	if err != nil {
+		return "", "", err
+	}
+
+	hash, err = s.refreshTokenGenerator.Hash(secret)
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, hash, nil
+}
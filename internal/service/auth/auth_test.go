@@ -0,0 +1,221 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/passwordhash/jwt-test-task/internal/service/auth"
+	svcErr "github.com/passwordhash/jwt-test-task/internal/service/errors"
+	"github.com/passwordhash/jwt-test-task/pkg/clock"
+	"github.com/passwordhash/jwt-test-task/pkg/hasher"
+	"github.com/passwordhash/jwt-test-task/pkg/jwt"
+)
+
+const (
+	testUserID     = "7f1c1f0e-2f4c-4a8e-9f7a-3c1d2b4e5f60"
+	testRemoteAddr = "192.0.2.1:40000"
+	testUserAgent  = "test-agent"
+	testAccessTTL  = 15 * time.Minute
+)
+
+var testNow = time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestService(t *testing.T, c clock.Clock, opts ...auth.Option) (*auth.Service, *memStorage) {
+	t.Helper()
+
+	storage := newMemStorage(c)
+
+	return newTestInstance(t, c, storage, opts...), storage
+}
+
+// newTestInstance creates a service on top of the storage. Services created
+// on the same storage act as instances of one deployment.
+func newTestInstance(t *testing.T, c clock.Clock, storage *memStorage, opts ...auth.Option) *auth.Service {
+	t.Helper()
+
+	return newTestInstanceWithHasher(t, c, storage, hasher.BcryptHasher{Cost: bcrypt.MinCost}, opts...)
+}
+
+func newTestInstanceWithHasher(
+	t *testing.T,
+	c clock.Clock,
+	storage *memStorage,
+	h hasher.Hasher,
+	opts ...auth.Option,
+) *auth.Service {
+	t.Helper()
+
+	key, err := jwt.NewHMACKey(jwt.HS256, []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewHMACKey() error = %v", err)
+	}
+
+	keys := jwt.NewKeyring()
+	if err := keys.AddSigningKey("test", key); err != nil {
+		t.Fatalf("AddSigningKey() error = %v", err)
+	}
+	if err := keys.SetActive("test"); err != nil {
+		t.Fatalf("SetActive() error = %v", err)
+	}
+
+	return auth.New(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		storage,
+		storage,
+		storage,
+		auth.NewRefreshTokenManager(
+			[]byte("refresh-token-test-key"),
+			auth.WithHasher(h),
+		),
+		storage,
+		testAccessTTL,
+		keys,
+		append([]auth.Option{auth.WithClock(c)}, opts...)...,
+	)
+}
+
+func TestIdentifyAccessTTL(t *testing.T) {
+	const leeway = 5 * time.Second
+
+	tests := []struct {
+		name    string
+		after   time.Duration
+		leeway  time.Duration
+		wantErr error
+	}{
+		{name: "issued", after: 0},
+		{name: "one second before ttl", after: testAccessTTL - time.Second},
+		{name: "exactly at ttl", after: testAccessTTL},
+		{name: "one second after ttl", after: testAccessTTL + time.Second, wantErr: jwt.ErrTokenExpired},
+		{name: "ttl plus leeway", after: testAccessTTL + leeway, leeway: leeway},
+		{
+			name:    "ttl plus leeway plus one second",
+			after:   testAccessTTL + leeway + time.Second,
+			leeway:  leeway,
+			wantErr: jwt.ErrTokenExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := clock.NewFake(testNow)
+			svc, _ := newTestService(t, c, auth.WithLeeway(tt.leeway))
+
+			access, _, err := svc.GetPair(context.Background(), testUserID, testRemoteAddr, testUserAgent)
+			if err != nil {
+				t.Fatalf("GetPair() error = %v", err)
+			}
+
+			c.Advance(tt.after)
+
+			userID, _, err := svc.Identify(context.Background(), access)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Identify() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && userID != testUserID {
+				t.Errorf("Identify() userID = %q, want %q", userID, testUserID)
+			}
+		})
+	}
+}
+
+func TestRefreshAcceptsExpiredAccessToken(t *testing.T) {
+	c := clock.NewFake(testNow)
+	svc, _ := newTestService(t, c)
+
+	access, refresh, err := svc.GetPair(context.Background(), testUserID, testRemoteAddr, testUserAgent)
+	if err != nil {
+		t.Fatalf("GetPair() error = %v", err)
+	}
+
+	c.Advance(testAccessTTL + time.Hour)
+
+	newAccess, _, err := svc.Refresh(context.Background(), access, refresh, testRemoteAddr, testUserAgent)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	// The successor is issued at the fake time and lives for the full TTL.
+	c.Advance(testAccessTTL)
+
+	if _, _, err := svc.Identify(context.Background(), newAccess); err != nil {
+		t.Errorf("Identify() error = %v", err)
+	}
+
+	if _, _, err := svc.Identify(context.Background(), access); !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Errorf("Identify() with the old token error = %v, want %v", err, jwt.ErrTokenExpired)
+	}
+}
+
+func TestIdentifyRevokedSession(t *testing.T) {
+	tests := []struct {
+		name      string
+		refreshes int
+	}{
+		{name: "never refreshed", refreshes: 0},
+		{name: "refreshed", refreshes: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := clock.NewFake(testNow)
+			svc, storage := newTestService(t, c)
+
+			access, refresh, err := svc.GetPair(ctx, testUserID, testRemoteAddr, testUserAgent)
+			if err != nil {
+				t.Fatalf("GetPair() error = %v", err)
+			}
+
+			issued := []string{access}
+			firstAccess, firstRefresh := access, refresh
+			for range tt.refreshes {
+				c.Advance(time.Minute)
+
+				access, refresh, err = svc.Refresh(ctx, access, refresh, testRemoteAddr, testUserAgent)
+				if err != nil {
+					t.Fatalf("Refresh() error = %v", err)
+				}
+				issued = append(issued, access)
+			}
+
+			_, tokenID, err := svc.Identify(ctx, access)
+			if err != nil {
+				t.Fatalf("Identify() error = %v", err)
+			}
+
+			if err := svc.RevokeRefreshToken(ctx, testUserID, tokenID); err != nil {
+				t.Fatalf("RevokeRefreshToken() error = %v", err)
+			}
+
+			// Another instance has nothing cached and no denylist, it relies on
+			// the storage alone.
+			other := newTestInstance(t, c, storage)
+
+			for i, token := range issued {
+				for name, instance := range map[string]*auth.Service{"this": svc, "other": other} {
+					if _, _, err := instance.Identify(ctx, token); !errors.Is(err, svcErr.ErrSessionRevoked) {
+						t.Errorf("Identify() of access token #%d on %s instance error = %v, want %v",
+							i, name, err, svcErr.ErrSessionRevoked)
+					}
+				}
+			}
+
+			if tt.refreshes == 0 {
+				return
+			}
+
+			// A rotated token of the revoked session is still detected as reused.
+			_, _, err = svc.Refresh(ctx, firstAccess, firstRefresh, testRemoteAddr, testUserAgent)
+			if !errors.Is(err, svcErr.ErrRefreshTokenReused) {
+				t.Errorf("Refresh() with a rotated token error = %v, want %v", err, svcErr.ErrRefreshTokenReused)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/passwordhash/jwt-test-task/pkg/clock"
+	"github.com/passwordhash/jwt-test-task/pkg/jwt"
+)
+
+type Option func(*Service)
+
+// WithNewIPEvents enables webhook events for refreshes made from an IP that
+// differs from the one the refresh token was issued to. The events are
+// written to the outbox together with the rotated token.
+func WithNewIPEvents() Option {
+	return func(s *Service) {
+		s.newIPEvents = true
+	}
+}
+
+// WithReuseEvents enables webhook events for refreshes made with an already
+// rotated refresh token. The events are written to the outbox together with
+// the revocation of the token family.
+func WithReuseEvents() Option {
+	return func(s *Service) {
+		s.reuseEvents = true
+	}
+}
+
+// WithRevocationCacheTTL sets how long the revocation status of an access
+// token is cached.
+func WithRevocationCacheTTL(ttl time.Duration) Option {
+	return func(s *Service) {
+		s.revocations = newRevocationCache(ttl)
+	}
+}
+
+// WithRefreshGracePeriod sets how long a rotated refresh token keeps
+// returning the pair it was rotated to. It lets clients refresh
+// concurrently, e.g. from parallel tabs, without the second refresh being
+// taken for token reuse. The pair is sealed on the rotated record, so it
+// is returned by every instance of the service. Zero, the default,
+// disables the grace period.
+func WithRefreshGracePeriod(d time.Duration) Option {
+	return func(s *Service) {
+		s.refreshGracePeriod = d
+		s.rotations = newRotationCache(d)
+	}
+}
+
+// WithDenylist makes revocations visible to the other instances of the
+// service without waiting for their revocation caches to expire.
+func WithDenylist(d Denylist) Option {
+	return func(s *Service) {
+		s.denylist = d
+	}
+}
+
+// WithMaxSessions caps the number of active sessions per user. When a new
+// session goes over the cap, the least recently refreshed ones are revoked
+// together with their rotated tokens. Zero, the default, means no cap.
+func WithMaxSessions(n int) Option {
+	return func(s *Service) {
+		s.maxSessions = n
+	}
+}
+
+// WithIssuer sets the iss claim of issued access tokens and requires it on
+// the parsed ones.
+func WithIssuer(iss string) Option {
+	return func(s *Service) {
+		s.issuer = iss
+	}
+}
+
+// WithAudience sets the aud claim of issued access tokens and requires it on
+// the parsed ones.
+func WithAudience(aud string) Option {
+	return func(s *Service) {
+		s.audience = aud
+	}
+}
+
+// WithLeeway sets the allowed clock skew for the access token time claims.
+func WithLeeway(leeway time.Duration) Option {
+	return func(s *Service) {
+		s.leeway = leeway
+	}
+}
+
+// WithClock sets the clock used to issue and validate tokens. It defaults to
+// the system clock.
+func WithClock(c clock.Clock) Option {
+	return func(s *Service) {
+		s.clock = c
+	}
+}
+
+// WithEncryption makes the service issue access tokens as signed-then-encrypted
+// nested JWTs. Plain signed tokens are still accepted.
+func WithEncryption(enc jwt.EncryptionKey, dec jwt.DecryptionKey) Option {
+	return func(s *Service) {
+		s.encryptionKey = enc
+		s.decryptionKey = dec
+	}
+}
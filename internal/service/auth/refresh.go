@@ -1,36 +1,196 @@
 package auth
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
 
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
+
+	"github.com/passwordhash/jwt-test-task/pkg/hasher"
+)
+
+const (
+	refreshSessionIDSize = 16
+	refreshSecretSize    = 32
+	refreshMACSize       = sha256.Size
+
+	refreshTokenSize = refreshSessionIDSize + refreshSecretSize + refreshMACSize
 )
 
+// sealKeyLabel separates the keys derived for Seal from the token MACs.
+const sealKeyLabel = "refresh-token-seal"
+
+// ErrMalformedRefreshToken is returned by RefreshTokenManager.Parse for
+// tokens that were not issued with the manager key or were altered.
+var ErrMalformedRefreshToken = errors.New("malformed refresh token")
+
 var _ RefreshTokenGenerator = (*RefreshTokenManager)(nil)
 
 // RefreshTokenManager is a struct that provides methods to manage refresh tokens.
 // Can be mocked for testing purposes.
-type RefreshTokenManager struct{}
+//
+// A refresh token is base64url(session ID || secret || MAC). The 16-byte
+// session ID is the ID of the stored record, so it is found without a
+// scan. The MAC is an HMAC-SHA256 of the session ID and the secret, so an
+// altered token is rejected before the storage is queried. Only the hash of
+// the secret is stored.
+type RefreshTokenManager struct {
+	key    []byte
+	hasher hasher.Hasher
+}
+
+type RefreshTokenManagerOption func(*RefreshTokenManager)
+
+// WithHasher sets the hasher of new refresh token secrets. It defaults to
+// bcrypt with the default cost. Secrets hashed by other hashers still
+// verify, see hasher.Compare.
+func WithHasher(h hasher.Hasher) RefreshTokenManagerOption {
+	return func(m *RefreshTokenManager) {
+		m.hasher = h
+	}
+}
+
+// NewRefreshTokenManager creates a RefreshTokenManager that authenticates
+// tokens with the key.
+func NewRefreshTokenManager(key []byte, opts ...RefreshTokenManagerOption) *RefreshTokenManager {
+	m := &RefreshTokenManager{
+		key:    key,
+		hasher: hasher.BcryptHasher{Cost: bcrypt.DefaultCost},
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Generate is a method that creates a new refresh token for the session.
+// It returns the token and its secret part, which is to be hashed.
+func (m *RefreshTokenManager) Generate(sessionID string) (token, secret string, err error) {
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid session ID: %w", err)
+	}
+
+	b := make([]byte, refreshSessionIDSize+refreshSecretSize, refreshTokenSize)
+	copy(b, id[:])
+	if _, err := rand.Read(b[refreshSessionIDSize:]); err != nil {
+		return "", "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+
+	b = append(b, m.mac(b)...)
+
+	return base64.RawURLEncoding.EncodeToString(b), encodeSecret(b), nil
+}
+
+// Parse is a method that checks the token MAC and returns the session ID
+// and the secret part of the token.
+func (m *RefreshTokenManager) Parse(token string) (sessionID, secret string, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(b) != refreshTokenSize {
+		return "", "", ErrMalformedRefreshToken
+	}
+
+	payload, mac := b[:refreshSessionIDSize+refreshSecretSize], b[refreshSessionIDSize+refreshSecretSize:]
+	if !hmac.Equal(mac, m.mac(payload)) {
+		return "", "", ErrMalformedRefreshToken
+	}
 
-// Generate is a method that creates a new random refresh token of the
-// specified length encoded in base64 URL format.
-func (RefreshTokenManager) Generate(length int) (string, error) {
-	b := make([]byte, length)
-	if _, err := rand.Read(b); err != nil {
-		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	id, err := uuid.FromBytes(b[:refreshSessionIDSize])
+	if err != nil {
+		return "", "", ErrMalformedRefreshToken
 	}
 
-	return base64.RawURLEncoding.EncodeToString(b), nil
+	return id.String(), encodeSecret(b), nil
 }
 
-// Hash is a method that hashes the provided refresh token using bcrypt.
-func (RefreshTokenManager) Hash(token string) (string, error) {
-	h, err := bcrypt.GenerateFromPassword([]byte(token), bcrypt.DefaultCost)
+// Hash is a method that hashes the provided refresh token secret with the
+// configured hasher. The hash is prefixed with its algorithm.
+func (m *RefreshTokenManager) Hash(secret string) (string, error) {
+	h, err := m.hasher.Hash(secret)
 	if err != nil {
 		return "", fmt.Errorf("failed to hash refresh token: %w", err)
 	}
 
-	return string(h), nil
+	return h, nil
+}
+
+// Compare is a method that checks the provided refresh token secret against
+// its hash, whichever algorithm made it.
+func (m *RefreshTokenManager) Compare(secret, hash string) error {
+	if err := hasher.Compare(secret, hash); err != nil {
+		return fmt.Errorf("failed to compare refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// Seal is a method that encrypts data with a key derived from the manager
+// key and the refresh token secret. Only a holder of the token can have it
+// opened, even with the sealed data at hand.
+func (m *RefreshTokenManager) Seal(secret string, data []byte) ([]byte, error) {
+	aead, err := m.sealCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return aead.Seal(nonce, nonce, data, nil), nil
+}
+
+// Open is a method that decrypts data sealed by Seal with the same secret.
+func (m *RefreshTokenManager) Open(secret string, sealed []byte) ([]byte, error) {
+	aead, err := m.sealCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("failed to open sealed data: too short")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	data, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sealed data: %w", err)
+	}
+
+	return data, nil
+}
+
+func (m *RefreshTokenManager) sealCipher(secret string) (cipher.AEAD, error) {
+	h := hmac.New(sha256.New, m.key)
+	h.Write([]byte(sealKeyLabel))
+	h.Write([]byte(secret))
+
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func (m *RefreshTokenManager) mac(payload []byte) []byte {
+	h := hmac.New(sha256.New, m.key)
+	h.Write(payload)
+
+	return h.Sum(nil)
+}
+
+func encodeSecret(token []byte) string {
+	return base64.RawURLEncoding.EncodeToString(token[refreshSessionIDSize : refreshSessionIDSize+refreshSecretSize])
 }
@@ -0,0 +1,411 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/passwordhash/jwt-test-task/internal/domain/models"
+	"github.com/passwordhash/jwt-test-task/internal/service/auth"
+	svcErr "github.com/passwordhash/jwt-test-task/internal/service/errors"
+	"github.com/passwordhash/jwt-test-task/pkg/clock"
+	"github.com/passwordhash/jwt-test-task/pkg/hasher"
+)
+
+const testGracePeriod = 10 * time.Second
+
+type pair struct {
+	access, refresh string
+}
+
+// activeSessionID returns the ID of the only active record.
+func activeSessionID(t *testing.T, storage *memStorage) string {
+	t.Helper()
+
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	for id, token := range storage.tokens {
+		if token.UsedAt == nil && !token.IsRevoked {
+			return id
+		}
+	}
+
+	t.Fatal("no active session")
+
+	return ""
+}
+
+func TestRefreshTokenDeletedBeforeRotation(t *testing.T) {
+	ctx := context.Background()
+	svc, storage := newTestService(t, clock.NewFake(testNow))
+
+	access, refresh, err := svc.GetPair(ctx, testUserID, testRemoteAddr, testUserAgent)
+	if err != nil {
+		t.Fatalf("GetPair() error = %v", err)
+	}
+
+	// The record is purged between the lookup and the rotation.
+	storage.beforeRotate = storage.delete
+
+	_, _, err = svc.Refresh(ctx, access, refresh, testRemoteAddr, testUserAgent)
+	if !errors.Is(err, svcErr.ErrInvalidRefreshToken) {
+		t.Errorf("Refresh() error = %v, want %v", err, svcErr.ErrInvalidRefreshToken)
+	}
+}
+
+func TestRefreshRejectsForgedTokenBeforeLookup(t *testing.T) {
+	ctx := context.Background()
+	c := clock.NewFake(testNow)
+	svc, storage := newTestService(t, c)
+
+	access, refresh, err := svc.GetPair(ctx, testUserID, testRemoteAddr, testUserAgent)
+	if err != nil {
+		t.Fatalf("GetPair() error = %v", err)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(refresh)
+	if err != nil {
+		t.Fatalf("DecodeString() error = %v", err)
+	}
+
+	// flip returns the token with one byte of its secret part changed.
+	flip := func(i int) string {
+		b := slices.Clone(raw)
+		b[i] ^= 0x01
+
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	// A well-formed token of the same session, MACed with another key.
+	reMACed, _, err := auth.NewRefreshTokenManager([]byte("another-refresh-token-key")).
+		Generate(activeSessionID(t, storage))
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "tampered session ID", token: flip(0)},
+		{name: "tampered secret", token: flip(20)},
+		{name: "tampered MAC", token: flip(len(raw) - 1)},
+		{name: "truncated", token: refresh[:len(refresh)-4]},
+		{name: "extended", token: refresh + "AAAA"},
+		{name: "re-MACed with another key", token: reMACed},
+		{name: "not base64", token: "!" + refresh[1:]},
+		{name: "empty", token: ""},
+	}
+
+	storage.beforeByID = func(id string) {
+		t.Errorf("ByID(%q) called for a forged refresh token", id)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := svc.Refresh(ctx, access, tt.token, testRemoteAddr, testUserAgent)
+			if !errors.Is(err, svcErr.ErrInvalidRefreshToken) {
+				t.Errorf("Refresh() error = %v, want %v", err, svcErr.ErrInvalidRefreshToken)
+			}
+		})
+	}
+}
+
+func TestRefreshConcurrentOnOneInstance(t *testing.T) {
+	const refreshes = 20
+
+	ctx := context.Background()
+	svc, storage := newTestService(t, clock.NewFake(testNow), auth.WithRefreshGracePeriod(testGracePeriod))
+
+	access, refresh, err := svc.GetPair(ctx, testUserID, testRemoteAddr, testUserAgent)
+	if err != nil {
+		t.Fatalf("GetPair() error = %v", err)
+	}
+	id := activeSessionID(t, storage)
+
+	results := make([]pair, refreshes)
+	errs := make([]error, refreshes)
+
+	var wg sync.WaitGroup
+	for i := range refreshes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i].access, results[i].refresh, errs[i] = svc.Refresh(
+				ctx, access, refresh, testRemoteAddr, testUserAgent,
+			)
+		}()
+	}
+	wg.Wait()
+
+	for i := range refreshes {
+		if errs[i] != nil {
+			t.Fatalf("Refresh() #%d error = %v", i, errs[i])
+		}
+		if results[i] != results[0] {
+			t.Errorf("Refresh() #%d returned another pair", i)
+		}
+	}
+
+	if n := storage.successors(id); n != 1 {
+		t.Errorf("token rotated %d times, want 1", n)
+	}
+}
+
+func TestRefreshConcurrentOnInstances(t *testing.T) {
+	ctx := context.Background()
+	c := clock.NewFake(testNow)
+	storage := newMemStorage(c)
+
+	instances := []*auth.Service{
+		newTestInstance(t, c, storage, auth.WithRefreshGracePeriod(testGracePeriod)),
+		newTestInstance(t, c, storage, auth.WithRefreshGracePeriod(testGracePeriod)),
+	}
+
+	access, refresh, err := instances[0].GetPair(ctx, testUserID, testRemoteAddr, testUserAgent)
+	if err != nil {
+		t.Fatalf("GetPair() error = %v", err)
+	}
+	id := activeSessionID(t, storage)
+
+	// Both instances read the record before either rotates it, so one of
+	// the rotations loses the race in the storage.
+	var arrived sync.WaitGroup
+	arrived.Add(len(instances))
+	storage.beforeRotate = func(string) {
+		arrived.Done()
+		arrived.Wait()
+	}
+
+	results := make([]pair, len(instances))
+	errs := make([]error, len(instances))
+
+	var wg sync.WaitGroup
+	for i, svc := range instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i].access, results[i].refresh, errs[i] = svc.Refresh(
+				ctx, access, refresh, testRemoteAddr, testUserAgent,
+			)
+		}()
+	}
+	wg.Wait()
+
+	for i := range instances {
+		if errs[i] != nil {
+			t.Fatalf("Refresh() on instance %d error = %v", i, errs[i])
+		}
+	}
+	if results[0] != results[1] {
+		t.Errorf("instances returned different pairs")
+	}
+
+	if n := storage.successors(id); n != 1 {
+		t.Errorf("token rotated %d times, want 1", n)
+	}
+
+	if _, _, err := instances[1].Identify(ctx, results[0].access); err != nil {
+		t.Errorf("Identify() of the successor error = %v", err)
+	}
+}
+
+func TestRefreshGracePeriodOnAnotherInstance(t *testing.T) {
+	tests := []struct {
+		name    string
+		grace   time.Duration
+		after   time.Duration
+		wantErr error
+	}{
+		{name: "right after rotation", grace: testGracePeriod, after: 0},
+		{name: "exactly at grace period", grace: testGracePeriod, after: testGracePeriod},
+		{
+			name:    "after grace period",
+			grace:   testGracePeriod,
+			after:   testGracePeriod + time.Second,
+			wantErr: svcErr.ErrRefreshTokenReused,
+		},
+		{name: "grace period disabled", grace: 0, after: 0, wantErr: svcErr.ErrRefreshTokenReused},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := clock.NewFake(testNow)
+			storage := newMemStorage(c)
+
+			first := newTestInstance(t, c, storage, auth.WithRefreshGracePeriod(tt.grace))
+
+			access, refresh, err := first.GetPair(ctx, testUserID, testRemoteAddr, testUserAgent)
+			if err != nil {
+				t.Fatalf("GetPair() error = %v", err)
+			}
+
+			rotatedAccess, rotatedRefresh, err := first.Refresh(ctx, access, refresh, testRemoteAddr, testUserAgent)
+			if err != nil {
+				t.Fatalf("Refresh() error = %v", err)
+			}
+
+			c.Advance(tt.after)
+
+			// A new instance has no memory of the rotation, as after a restart.
+			second := newTestInstance(t, c, storage, auth.WithRefreshGracePeriod(tt.grace))
+
+			gotAccess, gotRefresh, err := second.Refresh(ctx, access, refresh, testRemoteAddr, testUserAgent)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Refresh() on another instance error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				if _, _, err := second.Identify(ctx, rotatedAccess); !errors.Is(err, svcErr.ErrSessionRevoked) {
+					t.Errorf("Identify() of the successor error = %v, want %v", err, svcErr.ErrSessionRevoked)
+				}
+
+				return
+			}
+
+			if gotAccess != rotatedAccess || gotRefresh != rotatedRefresh {
+				t.Fatalf("Refresh() on another instance returned another pair")
+			}
+
+			if _, _, err := second.Refresh(ctx, gotAccess, gotRefresh, testRemoteAddr, testUserAgent); err != nil {
+				t.Errorf("Refresh() with the returned pair error = %v", err)
+			}
+		})
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	ctx := context.Background()
+	c := clock.NewFake(testNow)
+	denylist := newMemDenylist()
+	svc, storage := newTestService(t, c,
+		auth.WithRefreshGracePeriod(testGracePeriod),
+		auth.WithReuseEvents(),
+		auth.WithDenylist(denylist),
+	)
+
+	firstAccess, firstRefresh, err := svc.GetPair(ctx, testUserID, testRemoteAddr, testUserAgent)
+	if err != nil {
+		t.Fatalf("GetPair() error = %v", err)
+	}
+	familyID := activeSessionID(t, storage)
+
+	// Another session of the user must survive the reuse.
+	otherAccess, _, err := svc.GetPair(ctx, testUserID, testRemoteAddr, "other-agent")
+	if err != nil {
+		t.Fatalf("GetPair() error = %v", err)
+	}
+
+	// The family grows to three records: the reused token, its child and
+	// the active grandchild.
+	issued := []string{firstAccess}
+	access, refresh := firstAccess, firstRefresh
+	for range 2 {
+		c.Advance(time.Minute)
+
+		access, refresh, err = svc.Refresh(ctx, access, refresh, testRemoteAddr, testUserAgent)
+		if err != nil {
+			t.Fatalf("Refresh() error = %v", err)
+		}
+		issued = append(issued, access)
+	}
+
+	c.Advance(testGracePeriod + time.Second)
+
+	_, _, err = svc.Refresh(ctx, firstAccess, firstRefresh, "198.51.100.7:50000", testUserAgent)
+	if !errors.Is(err, svcErr.ErrRefreshTokenReused) {
+		t.Fatalf("Refresh() with a rotated token error = %v, want %v", err, svcErr.ErrRefreshTokenReused)
+	}
+
+	family := storage.family(familyID)
+	if len(family) != 3 {
+		t.Fatalf("family has %d records, want 3", len(family))
+	}
+	for _, token := range family {
+		if !token.IsRevoked {
+			t.Errorf("record %s is not revoked", token.ID)
+		}
+		if !denylist.Contains(token.TokenID) {
+			t.Errorf("access token ID of record %s is not denylisted", token.ID)
+		}
+	}
+
+	for i, token := range issued {
+		if _, _, err := svc.Identify(ctx, token); !errors.Is(err, svcErr.ErrSessionRevoked) {
+			t.Errorf("Identify() of access token #%d error = %v, want %v", i, err, svcErr.ErrSessionRevoked)
+		}
+	}
+
+	if _, _, err := svc.Refresh(ctx, access, refresh, testRemoteAddr, testUserAgent); err == nil {
+		t.Error("Refresh() with the active token of the revoked family succeeded")
+	}
+
+	if _, _, err := svc.Identify(ctx, otherAccess); err != nil {
+		t.Errorf("Identify() of the other session error = %v", err)
+	}
+
+	storage.mu.Lock()
+	events := slices.Clone(storage.events)
+	storage.mu.Unlock()
+
+	if len(events) != 1 || events[0].Type != models.EventTypeTokenReused {
+		t.Fatalf("events = %+v, want one %s event", events, models.EventTypeTokenReused)
+	}
+
+	var event models.TokenReusedEvent
+	if err := json.Unmarshal(events[0].Payload, &event); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	want := models.TokenReusedEvent{
+		UserID:    testUserID,
+		FamilyID:  familyID,
+		IP:        "198.51.100.7",
+		UserAgent: testUserAgent,
+		Timestamp: c.Now(),
+	}
+	if event != want {
+		t.Errorf("event = %+v, want %+v", event, want)
+	}
+}
+
+func TestRefreshUpgradesHash(t *testing.T) {
+	ctx := context.Background()
+	c := clock.NewFake(testNow)
+	storage := newMemStorage(c)
+
+	old := newTestInstanceWithHasher(t, c, storage, hasher.BcryptHasher{Cost: bcrypt.MinCost})
+
+	access, refresh, err := old.GetPair(ctx, testUserID, testRemoteAddr, testUserAgent)
+	if err != nil {
+		t.Fatalf("GetPair() error = %v", err)
+	}
+
+	// The deployment switches to scrypt, the bcrypt hash must still verify.
+	current := hasher.ScryptHasher{N: 1024, R: 8, P: 1, KeyLen: 32}
+	svc := newTestInstanceWithHasher(t, c, storage, current)
+
+	if _, _, err := svc.Refresh(ctx, access, refresh, testRemoteAddr, testUserAgent); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	stored, err := storage.ByID(ctx, activeSessionID(t, storage))
+	if err != nil {
+		t.Fatalf("ByID() error = %v", err)
+	}
+
+	// The parameters of current in the PHC format.
+	if !strings.HasPrefix(stored.TokenHash, "$scrypt$ln=10,r=8,p=1$") {
+		t.Errorf("successor hash %q was not made with the current hasher", stored.TokenHash)
+	}
+}
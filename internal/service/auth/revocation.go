@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultRevocationCacheTTL = 30 * time.Second
+
+	// revocationCacheSize bounds the cache. When it is full, expired
+	// entries are dropped; if none are expired, the cache is reset.
+	revocationCacheSize = 100_000
+)
+
+// revocationCache keeps the revocation status of access token IDs, so
+// identifying a user doesn't query the database on every request. Sessions
+// revoked by this instance are marked immediately; revocations made
+// elsewhere become visible once the cached entry expires.
+type revocationCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[string]revocationEntry
+}
+
+type revocationEntry struct {
+	revoked   bool
+	expiresAt time.Time
+}
+
+func newRevocationCache(ttl time.Duration) *revocationCache {
+	return &revocationCache{
+		ttl:     ttl,
+		entries: make(map[string]revocationEntry),
+	}
+}
+
+// get returns the cached status of the token ID and whether it was found.
+func (c *revocationCache) get(tokenID string, now time.Time) (revoked, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, ok := c.entries[tokenID]
+	if !ok || now.After(e.expiresAt) {
+		return false, false
+	}
+
+	return e.revoked, true
+}
+
+func (c *revocationCache) set(tokenID string, revoked bool, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= revocationCacheSize {
+		c.prune(now)
+	}
+
+	c.entries[tokenID] = revocationEntry{
+		revoked:   revoked,
+		expiresAt: now.Add(c.ttl),
+	}
+}
+
+func (c *revocationCache) markRevoked(tokenIDs []string, now time.Time) {
+	for _, id := range tokenIDs {
+		c.set(id, true, now)
+	}
+}
+
+func (c *revocationCache) prune(now time.Time) {
+	for id, e := range c.entries {
+		if now.After(e.expiresAt) {
+			delete(c.entries, id)
+		}
+	}
+
+	if len(c.entries) >= revocationCacheSize {
+		c.entries = make(map[string]revocationEntry)
+	}
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rotationCacheSize bounds the number of finished rotations kept for the
+// grace period. In-flight rotations are never dropped.
+const rotationCacheSize = 100_000
+
+// rotationCache makes refreshes with the same token idempotent on this
+// instance. Concurrent refreshes of a token wait for the first one and get
+// its pair; later refreshes get the same pair until the grace period is
+// over without opening the pair sealed on the record. Refreshes on other
+// instances, or after a restart, are served from the sealed pair.
+type rotationCache struct {
+	mu      sync.Mutex
+	grace   time.Duration
+	entries map[string]*rotation
+}
+
+// rotation is the rotation of a refresh token record. done is closed once
+// the result fields are set.
+type rotation struct {
+	done chan struct{}
+
+	access    string
+	refresh   string
+	err       error
+	expiresAt time.Time
+}
+
+func newRotationCache(grace time.Duration) *rotationCache {
+	return &rotationCache{
+		grace:   grace,
+		entries: make(map[string]*rotation),
+	}
+}
+
+// begin returns the rotation of the refresh token record id. leader reports
+// whether the caller started it; the leader must call finish.
+func (c *rotationCache) begin(id string, now time.Time) (r *rotation, leader bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if r, ok := c.get(id, now); ok {
+		return r, false
+	}
+
+	if len(c.entries) >= rotationCacheSize {
+		c.prune(now)
+	}
+
+	r = &rotation{done: make(chan struct{})}
+	c.entries[id] = r
+
+	return r, true
+}
+
+// lookup returns the in-flight or recently finished rotation of the refresh
+// token record id.
+func (c *rotationCache) lookup(id string, now time.Time) (*rotation, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.get(id, now)
+}
+
+// finish stores the result of the rotation and wakes up the waiting
+// refreshes. A failed rotation is forgotten, so it can be retried.
+func (c *rotationCache) finish(id string, r *rotation, access, refresh string, err error, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r.access, r.refresh, r.err = access, refresh, err
+	r.expiresAt = now.Add(c.grace)
+	close(r.done)
+
+	if err != nil || c.grace <= 0 {
+		delete(c.entries, id)
+	}
+}
+
+func (c *rotationCache) get(id string, now time.Time) (*rotation, bool) {
+	r, ok := c.entries[id]
+	if !ok || r.finished() && now.After(r.expiresAt) {
+		return nil, false
+	}
+
+	return r, true
+}
+
+func (c *rotationCache) prune(now time.Time) {
+	for id, r := range c.entries {
+		if r.finished() && now.After(r.expiresAt) {
+			delete(c.entries, id)
+		}
+	}
+}
+
+// wait returns the pair issued by the rotation once it is finished.
+func (r *rotation) wait(ctx context.Context) (access, refresh string, err error) {
+	select {
+	case <-r.done:
+		return r.access, r.refresh, r.err
+	case <-ctx.Done():
+		return "", "", ctx.Err()
+	}
+}
+
+func (r *rotation) finished() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	"github.com/passwordhash/jwt-test-task/internal/domain/models"
+	svcErr "github.com/passwordhash/jwt-test-task/internal/service/errors"
+	repoErr "github.com/passwordhash/jwt-test-task/internal/storage/errors"
+)
+
+// Sessions returns the active sessions of the user. The session the access
+// token currentTokenID was issued for is marked as current, whether it was
+// issued before the last refresh or not.
+func (s *Service) Sessions(ctx context.Context, userID, currentTokenID string) ([]models.Session, error) {
+	const op = "tokens.service.Sessions"
+
+	log := s.log.With("op", op, "userID", userID)
+
+	if _, err := uuid.Parse(userID); err != nil {
+		log.Warn("invalid user ID", slog.Any("error", err))
+
+		return nil, svcErr.ErrInvalidID
+	}
+
+	sessions, err := s.refreshProvider.SessionsByUserID(ctx, userID)
+	if err != nil {
+		log.Error("failed to get active sessions", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if currentTokenID == "" {
+		return sessions, nil
+	}
+
+	current, err := s.refreshProvider.ByTokenID(ctx, currentTokenID)
+	if errors.Is(err, repoErr.ErrRefreshTokenNotFound) {
+		return sessions, nil
+	}
+	if err != nil {
+		log.Error("failed to get current session", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current.FamilyID
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession revokes the user's session with the given ID, as returned
+// by Sessions. The access tokens of the session, including the ones issued
+// before its last refresh, stop being accepted right away.
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	const op = "tokens.service.RevokeSession"
+
+	log := s.log.With("op", op, "userID", userID, "sessionID", sessionID)
+
+	if _, err := uuid.Parse(userID); err != nil {
+		log.Warn("invalid user ID", slog.Any("error", err))
+
+		return svcErr.ErrInvalidID
+	}
+
+	if _, err := uuid.Parse(sessionID); err != nil {
+		log.Warn("invalid session ID", slog.Any("error", err))
+
+		return svcErr.ErrInvalidID
+	}
+
+	tokenIDs, err := s.refreshTokenRevoker.RevokeSession(ctx, userID, sessionID)
+	if errors.Is(err, repoErr.ErrRefreshTokenNotFound) {
+		log.Warn("session not found")
+
+		return svcErr.ErrSessionNotFound
+	}
+	if err != nil {
+		log.Error("failed to revoke session", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.markRevoked(ctx, log, tokenIDs)
+
+	log.Info("session revoked successfully")
+
+	return nil
+}
+
+// RevokeOtherSessions revokes every session of the user except the one the
+// access token currentTokenID was issued for. It returns the number of
+// revoked sessions.
+func (s *Service) RevokeOtherSessions(ctx context.Context, userID, currentTokenID string) (int, error) {
+	const op = "tokens.service.RevokeOtherSessions"
+
+	log := s.log.With("op", op, "userID", userID)
+
+	if _, err := uuid.Parse(userID); err != nil {
+		log.Warn("invalid user ID", slog.Any("error", err))
+
+		return 0, svcErr.ErrInvalidID
+	}
+
+	if currentTokenID == "" {
+		log.Warn("no current session")
+
+		return 0, svcErr.ErrInvalidAccessToken
+	}
+
+	tokenIDs, revoked, err := s.refreshTokenRevoker.RevokeOthers(ctx, userID, currentTokenID)
+	if err != nil {
+		log.Error("failed to revoke other sessions", slog.Any("error", err))
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.markRevoked(ctx, log, tokenIDs)
+
+	log.Info("other sessions revoked successfully", slog.Int("count", revoked))
+
+	return revoked, nil
+}
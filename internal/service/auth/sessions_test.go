@@ -0,0 +1,252 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/passwordhash/jwt-test-task/internal/service/auth"
+	svcErr "github.com/passwordhash/jwt-test-task/internal/service/errors"
+	"github.com/passwordhash/jwt-test-task/pkg/clock"
+)
+
+func TestRevokeOtherSessions(t *testing.T) {
+	ctx := context.Background()
+	c := clock.NewFake(testNow)
+	svc, storage := newTestService(t, c)
+
+	// The current session is rotated twice, so it has two used ancestors.
+	firstAccess, firstRefresh, err := svc.GetPair(ctx, testUserID, testRemoteAddr, testUserAgent)
+	if err != nil {
+		t.Fatalf("GetPair() error = %v", err)
+	}
+
+	access, refresh := firstAccess, firstRefresh
+	for range 2 {
+		c.Advance(time.Minute)
+
+		access, refresh, err = svc.Refresh(ctx, access, refresh, testRemoteAddr, testUserAgent)
+		if err != nil {
+			t.Fatalf("Refresh() error = %v", err)
+		}
+	}
+
+	// The other session is refreshed once, so its first access token was
+	// issued with a rotated refresh token.
+	otherFirstAccess, otherRefresh, err := svc.GetPair(ctx, testUserID, testRemoteAddr, "other-agent")
+	if err != nil {
+		t.Fatalf("GetPair() error = %v", err)
+	}
+
+	c.Advance(time.Minute)
+
+	otherAccess, _, err := svc.Refresh(ctx, otherFirstAccess, otherRefresh, testRemoteAddr, "other-agent")
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	_, currentTokenID, err := svc.Identify(ctx, access)
+	if err != nil {
+		t.Fatalf("Identify() error = %v", err)
+	}
+
+	revoked, err := svc.RevokeOtherSessions(ctx, testUserID, currentTokenID)
+	if err != nil {
+		t.Fatalf("RevokeOtherSessions() error = %v", err)
+	}
+	if revoked != 1 {
+		t.Errorf("RevokeOtherSessions() = %d, want 1, rotated tokens are not sessions", revoked)
+	}
+
+	for i, token := range []string{otherFirstAccess, otherAccess} {
+		if _, _, err := svc.Identify(ctx, token); !errors.Is(err, svcErr.ErrSessionRevoked) {
+			t.Errorf("Identify() of access token #%d of the other session error = %v, want %v",
+				i, err, svcErr.ErrSessionRevoked)
+		}
+	}
+	if _, _, err := svc.Identify(ctx, access); err != nil {
+		t.Errorf("Identify() of the current session error = %v", err)
+	}
+	if _, _, err := svc.Identify(ctx, firstAccess); err != nil {
+		t.Errorf("Identify() of the first access token of the current session error = %v", err)
+	}
+
+	// Another instance has nothing cached and no denylist, it relies on the
+	// storage alone.
+	other := newTestInstance(t, c, storage)
+	if _, _, err := other.Identify(ctx, otherFirstAccess); !errors.Is(err, svcErr.ErrSessionRevoked) {
+		t.Errorf("Identify() of the other session on another instance error = %v, want %v",
+			err, svcErr.ErrSessionRevoked)
+	}
+
+	// An ancestor of the current session is still detected as reused.
+	_, _, err = svc.Refresh(ctx, firstAccess, firstRefresh, testRemoteAddr, testUserAgent)
+	if !errors.Is(err, svcErr.ErrRefreshTokenReused) {
+		t.Errorf("Refresh() with a rotated token error = %v, want %v", err, svcErr.ErrRefreshTokenReused)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	ctx := context.Background()
+	c := clock.NewFake(testNow)
+	svc, storage := newTestService(t, c)
+
+	firstAccess, refresh, err := svc.GetPair(ctx, testUserID, testRemoteAddr, testUserAgent)
+	if err != nil {
+		t.Fatalf("GetPair() error = %v", err)
+	}
+
+	c.Advance(time.Minute)
+
+	access, _, err := svc.Refresh(ctx, firstAccess, refresh, testRemoteAddr, testUserAgent)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	_, tokenID, err := svc.Identify(ctx, access)
+	if err != nil {
+		t.Fatalf("Identify() error = %v", err)
+	}
+
+	sessions, err := svc.Sessions(ctx, testUserID, tokenID)
+	if err != nil {
+		t.Fatalf("Sessions() error = %v", err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("Sessions() returned %d sessions, want 1", len(sessions))
+	}
+
+	if err := svc.RevokeSession(ctx, testUserID, sessions[0].ID); err != nil {
+		t.Fatalf("RevokeSession() error = %v", err)
+	}
+
+	other := newTestInstance(t, c, storage)
+	for i, token := range []string{firstAccess, access} {
+		for name, instance := range map[string]*auth.Service{"this": svc, "other": other} {
+			if _, _, err := instance.Identify(ctx, token); !errors.Is(err, svcErr.ErrSessionRevoked) {
+				t.Errorf("Identify() of access token #%d on %s instance error = %v, want %v",
+					i, name, err, svcErr.ErrSessionRevoked)
+			}
+		}
+	}
+
+	err = svc.RevokeSession(ctx, testUserID, sessions[0].ID)
+	if !errors.Is(err, svcErr.ErrSessionNotFound) {
+		t.Errorf("RevokeSession() of a revoked session error = %v, want %v", err, svcErr.ErrSessionNotFound)
+	}
+}
+
+func TestSessionsAcrossRefreshes(t *testing.T) {
+	ctx := context.Background()
+	c := clock.NewFake(testNow)
+	svc, _ := newTestService(t, c)
+
+	firstAccess, refresh, err := svc.GetPair(ctx, testUserID, testRemoteAddr, testUserAgent)
+	if err != nil {
+		t.Fatalf("GetPair() error = %v", err)
+	}
+
+	if _, _, err := svc.GetPair(ctx, testUserID, testRemoteAddr, "other-agent"); err != nil {
+		t.Fatalf("GetPair() error = %v", err)
+	}
+
+	_, firstTokenID, err := svc.Identify(ctx, firstAccess)
+	if err != nil {
+		t.Fatalf("Identify() error = %v", err)
+	}
+
+	before, err := svc.Sessions(ctx, testUserID, firstTokenID)
+	if err != nil {
+		t.Fatalf("Sessions() error = %v", err)
+	}
+
+	access := firstAccess
+	for range 2 {
+		c.Advance(time.Minute)
+
+		access, refresh, err = svc.Refresh(ctx, access, refresh, "198.51.100.7:50000", testUserAgent)
+		if err != nil {
+			t.Fatalf("Refresh() error = %v", err)
+		}
+	}
+
+	// The first access token still identifies the refreshed session.
+	after, err := svc.Sessions(ctx, testUserID, firstTokenID)
+	if err != nil {
+		t.Fatalf("Sessions() error = %v", err)
+	}
+
+	if len(before) != 2 || len(after) != 2 {
+		t.Fatalf("Sessions() returned %d and %d sessions, want 2", len(before), len(after))
+	}
+
+	// The refreshed session is the most recently used one.
+	current := after[0]
+	if !current.Current || after[1].Current {
+		t.Errorf("Current = %v, %v, want true, false", current.Current, after[1].Current)
+	}
+
+	id := before[0].ID
+	if !before[0].Current {
+		id = before[1].ID
+	}
+	if current.ID != id {
+		t.Errorf("ID after refresh = %q, want %q", current.ID, id)
+	}
+	if !current.CreatedAt.Equal(testNow) {
+		t.Errorf("CreatedAt = %v, want %v", current.CreatedAt, testNow)
+	}
+	if want := testNow.Add(2 * time.Minute); !current.LastUsedAt.Equal(want) {
+		t.Errorf("LastUsedAt = %v, want %v", current.LastUsedAt, want)
+	}
+	if current.IP != "198.51.100.7" {
+		t.Errorf("IP = %q, want 198.51.100.7", current.IP)
+	}
+
+	// The ID listed before the refreshes still revokes the session.
+	if err := svc.RevokeSession(ctx, testUserID, id); err != nil {
+		t.Fatalf("RevokeSession() error = %v", err)
+	}
+
+	if _, _, err := svc.Identify(ctx, access); !errors.Is(err, svcErr.ErrSessionRevoked) {
+		t.Errorf("Identify() error = %v, want %v", err, svcErr.ErrSessionRevoked)
+	}
+}
+
+func TestMaxSessionsEvictsFamily(t *testing.T) {
+	ctx := context.Background()
+	c := clock.NewFake(testNow)
+	svc, _ := newTestService(t, c, auth.WithMaxSessions(1))
+
+	firstAccess, refresh, err := svc.GetPair(ctx, testUserID, testRemoteAddr, testUserAgent)
+	if err != nil {
+		t.Fatalf("GetPair() error = %v", err)
+	}
+
+	c.Advance(time.Minute)
+
+	access, _, err := svc.Refresh(ctx, firstAccess, refresh, testRemoteAddr, testUserAgent)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	c.Advance(time.Minute)
+
+	newAccess, _, err := svc.GetPair(ctx, testUserID, testRemoteAddr, "other-agent")
+	if err != nil {
+		t.Fatalf("GetPair() error = %v", err)
+	}
+
+	// The evicted session is revoked with the tokens issued before its refresh.
+	for i, token := range []string{firstAccess, access} {
+		if _, _, err := svc.Identify(ctx, token); !errors.Is(err, svcErr.ErrSessionRevoked) {
+			t.Errorf("Identify() of access token #%d of the evicted session error = %v, want %v",
+				i, err, svcErr.ErrSessionRevoked)
+		}
+	}
+
+	if _, _, err := svc.Identify(ctx, newAccess); err != nil {
+		t.Errorf("Identify() of the new session error = %v", err)
+	}
+}
@@ -0,0 +1,372 @@
+package auth_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/passwordhash/jwt-test-task/internal/domain/models"
+	repoErr "github.com/passwordhash/jwt-test-task/internal/storage/errors"
+	"github.com/passwordhash/jwt-test-task/pkg/clock"
+)
+
+// memStorage is an in-memory refresh token storage that mirrors the
+// semantics of the Postgres one.
+type memStorage struct {
+	mu     sync.Mutex
+	clock  clock.Clock
+	tokens map[string]*models.RefreshToken
+	events []models.OutboxEvent
+
+	// beforeByID and beforeRotate, if set, are called when ByID and Rotate
+	// are entered, before the record is looked up.
+	beforeByID   func(id string)
+	beforeRotate func(id string)
+}
+
+func newMemStorage(c clock.Clock) *memStorage {
+	return &memStorage{
+		clock:  c,
+		tokens: make(map[string]*models.RefreshToken),
+	}
+}
+
+func (m *memStorage) Save(
+	_ context.Context,
+	id, userID, tokenID, tokenHash, userAgent, ip string,
+	maxSessions int,
+) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock.Now()
+	m.tokens[id] = &models.RefreshToken{
+		ID:        id,
+		UserID:    userID,
+		TokenID:   tokenID,
+		TokenHash: tokenHash,
+		UserAgent: userAgent,
+		IP:        ip,
+		FamilyID:  id,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if maxSessions <= 0 {
+		return nil, nil
+	}
+
+	active := m.active(userID)
+	if len(active) <= maxSessions {
+		return nil, nil
+	}
+
+	families := make(map[string]bool)
+	for _, t := range active[maxSessions:] {
+		families[t.FamilyID] = true
+	}
+
+	var evicted []string
+	for _, t := range m.tokens {
+		if t.IsRevoked || !families[t.FamilyID] {
+			continue
+		}
+
+		t.IsRevoked = true
+		t.UpdatedAt = now
+		evicted = append(evicted, t.TokenID)
+	}
+
+	return evicted, nil
+}
+
+func (m *memStorage) ByID(_ context.Context, id string) (models.RefreshToken, error) {
+	if m.beforeByID != nil {
+		m.beforeByID(id)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.tokens[id]
+	if !ok {
+		return models.RefreshToken{}, repoErr.ErrRefreshTokenNotFound
+	}
+
+	return *t, nil
+}
+
+func (m *memStorage) ByTokenID(_ context.Context, tokenID string) (models.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.tokens {
+		if t.TokenID == tokenID {
+			return *t, nil
+		}
+	}
+
+	return models.RefreshToken{}, repoErr.ErrRefreshTokenNotFound
+}
+
+func (m *memStorage) SessionsByUserID(_ context.Context, userID string) ([]models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var sessions []models.Session
+	for _, t := range m.active(userID) {
+		createdAt := t.CreatedAt
+		for _, f := range m.tokens {
+			if f.FamilyID == t.FamilyID && f.CreatedAt.Before(createdAt) {
+				createdAt = f.CreatedAt
+			}
+		}
+
+		sessions = append(sessions, models.Session{
+			ID:         t.FamilyID,
+			UserAgent:  t.UserAgent,
+			IP:         t.IP,
+			CreatedAt:  createdAt,
+			LastUsedAt: t.CreatedAt,
+		})
+	}
+
+	return sessions, nil
+}
+
+func (m *memStorage) Rotate(
+	_ context.Context,
+	id, newID, tokenID, tokenHash, ip string,
+	successor []byte,
+	events ...models.OutboxEvent,
+) error {
+	if m.beforeRotate != nil {
+		m.beforeRotate(id)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.tokens[id]
+	if !ok {
+		return repoErr.ErrRefreshTokenNotFound
+	}
+	if t.IsRevoked || t.UsedAt != nil {
+		return repoErr.ErrRefreshTokenUsed
+	}
+
+	now := m.clock.Now()
+	t.UsedAt = &now
+	t.Successor = successor
+	t.UpdatedAt = now
+
+	m.tokens[newID] = &models.RefreshToken{
+		ID:        newID,
+		UserID:    t.UserID,
+		TokenID:   tokenID,
+		TokenHash: tokenHash,
+		UserAgent: t.UserAgent,
+		IP:        ip,
+		FamilyID:  t.FamilyID,
+		ParentID:  &t.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	m.events = append(m.events, events...)
+
+	return nil
+}
+
+func (m *memStorage) RevokeByTokenID(_ context.Context, userID, tokenID string) ([]string, error) {
+	familyID, ok := m.familyOf(func(t *models.RefreshToken) bool {
+		return t.UserID == userID && t.TokenID == tokenID
+	})
+	if !ok {
+		return nil, repoErr.ErrRefreshTokenNotFound
+	}
+
+	ids := m.revoke(func(t *models.RefreshToken) bool {
+		return t.UserID == userID && t.FamilyID == familyID
+	})
+	if len(ids) == 0 {
+		return nil, repoErr.ErrRefreshTokenNotFound
+	}
+
+	return ids, nil
+}
+
+// familyOf returns the family of the first token that matches.
+func (m *memStorage) familyOf(match func(t *models.RefreshToken) bool) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.tokens {
+		if match(t) {
+			return t.FamilyID, true
+		}
+	}
+
+	return "", false
+}
+
+func (m *memStorage) RevokeAll(_ context.Context, userID string) ([]string, error) {
+	return m.revoke(func(t *models.RefreshToken) bool {
+		return t.UserID == userID
+	}), nil
+}
+
+func (m *memStorage) RevokeFamily(
+	_ context.Context,
+	familyID string,
+	events ...models.OutboxEvent,
+) ([]string, error) {
+	ids := m.revoke(func(t *models.RefreshToken) bool {
+		return t.FamilyID == familyID
+	})
+
+	m.mu.Lock()
+	m.events = append(m.events, events...)
+	m.mu.Unlock()
+
+	return ids, nil
+}
+
+func (m *memStorage) RevokeSession(_ context.Context, userID, familyID string) ([]string, error) {
+	ids := m.revoke(func(t *models.RefreshToken) bool {
+		return t.UserID == userID && t.FamilyID == familyID
+	})
+	if len(ids) == 0 {
+		return nil, repoErr.ErrRefreshTokenNotFound
+	}
+
+	return ids, nil
+}
+
+func (m *memStorage) RevokeOthers(_ context.Context, userID, keepTokenID string) ([]string, int, error) {
+	keepFamilyID, _ := m.familyOf(func(t *models.RefreshToken) bool {
+		return t.UserID == userID && t.TokenID == keepTokenID
+	})
+
+	m.mu.Lock()
+	sessions := 0
+	for _, t := range m.active(userID) {
+		if t.FamilyID != keepFamilyID {
+			sessions++
+		}
+	}
+	m.mu.Unlock()
+
+	return m.revoke(func(t *models.RefreshToken) bool {
+		return t.UserID == userID && t.FamilyID != keepFamilyID
+	}), sessions, nil
+}
+
+// revoke revokes the tokens that are not revoked yet and match, and returns
+// their access token IDs.
+func (m *memStorage) revoke(match func(t *models.RefreshToken) bool) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var tokenIDs []string
+	for _, t := range m.tokens {
+		if t.IsRevoked || !match(t) {
+			continue
+		}
+
+		t.IsRevoked = true
+		t.UpdatedAt = m.clock.Now()
+		tokenIDs = append(tokenIDs, t.TokenID)
+	}
+
+	return tokenIDs
+}
+
+// active returns the active tokens of the user, newest first. It must be
+// called with mu held.
+func (m *memStorage) active(userID string) []*models.RefreshToken {
+	var active []*models.RefreshToken
+	for _, t := range m.tokens {
+		if t.UserID == userID && !t.IsRevoked && t.UsedAt == nil {
+			active = append(active, t)
+		}
+	}
+
+	slices.SortFunc(active, func(a, b *models.RefreshToken) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+
+		return strings.Compare(b.ID, a.ID)
+	})
+
+	return active
+}
+
+// memDenylist is an in-memory access token denylist.
+type memDenylist struct {
+	mu       sync.Mutex
+	tokenIDs map[string]struct{}
+}
+
+func newMemDenylist() *memDenylist {
+	return &memDenylist{tokenIDs: make(map[string]struct{})}
+}
+
+func (d *memDenylist) Contains(tokenID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, ok := d.tokenIDs[tokenID]
+
+	return ok
+}
+
+func (d *memDenylist) Add(_ context.Context, tokenIDs []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, id := range tokenIDs {
+		d.tokenIDs[id] = struct{}{}
+	}
+
+	return nil
+}
+
+// family returns copies of the records of the family.
+func (m *memStorage) family(familyID string) []models.RefreshToken {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var tokens []models.RefreshToken
+	for _, t := range m.tokens {
+		if t.FamilyID == familyID {
+			tokens = append(tokens, *t)
+		}
+	}
+
+	return tokens
+}
+
+// delete removes the record, as Purge would.
+func (m *memStorage) delete(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.tokens, id)
+}
+
+// successors returns the number of records the record id was rotated to.
+func (m *memStorage) successors(id string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for _, t := range m.tokens {
+		if t.ParentID != nil && *t.ParentID == id {
+			n++
+		}
+	}
+
+	return n
+}
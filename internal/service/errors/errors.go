@@ -3,5 +3,14 @@ package svcErr
 import "fmt"
 
 var (
-	ErrInvalidID = fmt.Errorf("invalid id format")
+	ErrInvalidID           = fmt.Errorf("invalid id format")
+	ErrInvalidAccessToken  = fmt.Errorf("invalid access token")
+	ErrInvalidRefreshToken = fmt.Errorf("invalid refresh token")
+	ErrTokenPairMismatch   = fmt.Errorf("access and refresh tokens were not issued together")
+	ErrRefreshTokenRevoked = fmt.Errorf("refresh token revoked")
+	ErrRefreshTokenUsed    = fmt.Errorf("refresh token already used")
+	ErrRefreshTokenReused  = fmt.Errorf("refresh token reuse detected, session revoked")
+	ErrUserAgentMismatch   = fmt.Errorf("user agent mismatch, all sessions revoked")
+	ErrSessionRevoked      = fmt.Errorf("session revoked")
+	ErrSessionNotFound     = fmt.Errorf("session not found")
 )
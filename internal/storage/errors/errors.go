@@ -6,4 +6,5 @@ import (
 
 var (
 	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenUsed     = errors.New("refresh token already used or revoked")
 )
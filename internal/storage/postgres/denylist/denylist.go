@@ -0,0 +1,155 @@
+package denylist
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/passwordhash/jwt-test-task/internal/domain/models"
+)
+
+// Channel is the notification channel new denylist entries are published
+// to, see the revoked_access_tokens insert trigger.
+const Channel = "access_token_revoked"
+
+type Storage struct {
+	pool *pgxpool.Pool
+}
+
+// New creates the denylist storage. It needs a pool rather than
+// postgres.DB, as listening holds a dedicated connection.
+func New(pool *pgxpool.Pool) *Storage {
+	return &Storage{
+		pool: pool,
+	}
+}
+
+// Add denylists the access token IDs for ttl. Every instance is notified
+// of the new entries. Already denylisted IDs are skipped.
+func (s *Storage) Add(ctx context.Context, tokenIDs []string, ttl time.Duration) error {
+	const op = "storage.denylist.Add"
+
+	if len(tokenIDs) == 0 {
+		return nil
+	}
+
+	query := `
+	INSERT INTO revoked_access_tokens (token_id, expires_at)
+	SELECT id, NOW() + make_interval(secs => $2)
+	FROM unnest($1::uuid[]) AS id
+	ON CONFLICT (token_id) DO NOTHING;
+	`
+
+	if _, err := s.pool.Exec(ctx, query, tokenIDs, ttl.Seconds()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Active returns the entries that have not expired yet.
+func (s *Storage) Active(ctx context.Context) ([]models.RevokedAccessToken, error) {
+	const op = "storage.denylist.Active"
+
+	query := `
+	SELECT token_id, EXTRACT(EPOCH FROM expires_at - NOW())::float8
+	FROM revoked_access_tokens
+	WHERE expires_at > NOW();
+	`
+
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tokens, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.RevokedAccessToken, error) {
+		var (
+			t       models.RevokedAccessToken
+			seconds float64
+		)
+		err := row.Scan(&t.TokenID, &seconds)
+		t.ExpiresIn = time.Duration(seconds * float64(time.Second))
+
+		return t, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+// DeleteExpired deletes the expired entries and returns their number.
+func (s *Storage) DeleteExpired(ctx context.Context) (int64, error) {
+	const op = "storage.denylist.DeleteExpired"
+
+	query := `
+	DELETE FROM revoked_access_tokens
+	WHERE expires_at <= NOW();
+	`
+
+	tag, err := s.pool.Exec(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// Listen subscribes to new denylist entries and calls fn for each of them
+// until ctx is done or the connection fails. ready is called once the
+// subscription is active, entries added before that are not delivered.
+func (s *Storage) Listen(ctx context.Context, ready func() error, fn func(models.RevokedAccessToken)) error {
+	const op = "storage.denylist.Listen"
+
+	pooled, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// The connection is taken out of the pool, so it is never reused in
+	// the listening state.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+Channel); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := ready(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if t, ok := parseNotification(n.Payload); ok {
+			fn(t)
+		}
+	}
+}
+
+func parseNotification(payload string) (models.RevokedAccessToken, bool) {
+	tokenID, seconds, ok := strings.Cut(payload, " ")
+	if !ok {
+		return models.RevokedAccessToken{}, false
+	}
+
+	secs, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil {
+		return models.RevokedAccessToken{}, false
+	}
+
+	return models.RevokedAccessToken{
+		TokenID:   tokenID,
+		ExpiresIn: time.Duration(secs) * time.Second,
+	}, true
+}
@@ -0,0 +1,46 @@
+package denylist
+
+import (
+	"testing"
+	"time"
+
+	"github.com/passwordhash/jwt-test-task/internal/domain/models"
+)
+
+func TestParseNotification(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    models.RevokedAccessToken
+		wantOK  bool
+	}{
+		{
+			name:    "valid",
+			payload: "3f2b8c1e-7d4a-4e6b-9c0d-1a2b3c4d5e6f 900",
+			want:    models.RevokedAccessToken{TokenID: "3f2b8c1e-7d4a-4e6b-9c0d-1a2b3c4d5e6f", ExpiresIn: 15 * time.Minute},
+			wantOK:  true,
+		},
+		{
+			name:    "already expired",
+			payload: "token -5",
+			want:    models.RevokedAccessToken{TokenID: "token", ExpiresIn: -5 * time.Second},
+			wantOK:  true,
+		},
+		{name: "empty", payload: ""},
+		{name: "no expiry", payload: "token"},
+		{name: "expiry not a number", payload: "token soon"},
+		{name: "fractional expiry", payload: "token 1.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseNotification(tt.payload)
+			if ok != tt.wantOK {
+				t.Fatalf("parseNotification(%q) ok = %v, want %v", tt.payload, ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("parseNotification(%q) = %+v, want %+v", tt.payload, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/passwordhash/jwt-test-task/internal/domain/models"
+	"github.com/passwordhash/jwt-test-task/pkg/postgres"
+)
+
+type Storage struct {
+	db postgres.DB
+}
+
+func New(db postgres.DB) *Storage {
+	return &Storage{
+		db: db,
+	}
+}
+
+// Claim returns up to limit pending events that are due for delivery. Claimed
+// events are postponed by lease, so other dispatchers don't pick them up
+// while the delivery is in progress.
+func (s *Storage) Claim(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	const op = "storage.outbox.Claim"
+
+	query := `
+	UPDATE webhook_outbox
+	SET next_attempt_at = NOW() + make_interval(secs => $2)
+	WHERE id IN (
+		SELECT id FROM webhook_outbox
+		WHERE status = 'pending' AND next_attempt_at <= NOW()
+		ORDER BY next_attempt_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, event_type, payload, attempts, created_at;
+	`
+
+	rows, err := s.db.Query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var events []models.OutboxEvent
+	for rows.Next() {
+		var e models.OutboxEvent
+		if err := rows.Scan(&e.ID, &e.Type, &e.Payload, &e.Attempts, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return events, nil
+}
+
+func (s *Storage) MarkDelivered(ctx context.Context, id string) error {
+	const op = "storage.outbox.MarkDelivered"
+
+	query := `
+	UPDATE webhook_outbox
+	SET status = 'delivered', attempts = attempts + 1, delivered_at = NOW(), last_error = NULL
+	WHERE id = $1;
+	`
+
+	if _, err := s.db.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// MarkFailed records a failed delivery attempt and schedules the next one
+// after retryIn.
+func (s *Storage) MarkFailed(ctx context.Context, id string, retryIn time.Duration, lastErr string) error {
+	const op = "storage.outbox.MarkFailed"
+
+	query := `
+	UPDATE webhook_outbox
+	SET attempts = attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2), last_error = $3
+	WHERE id = $1;
+	`
+
+	if _, err := s.db.Exec(ctx, query, id, retryIn.Seconds(), lastErr); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// MarkDead records the last failed attempt and moves the event to the
+// dead-letter state. Dead events are never retried.
+func (s *Storage) MarkDead(ctx context.Context, id string, lastErr string) error {
+	const op = "storage.outbox.MarkDead"
+
+	query := `
+	UPDATE webhook_outbox
+	SET status = 'dead', attempts = attempts + 1, last_error = $2
+	WHERE id = $1;
+	`
+
+	if _, err := s.db.Exec(ctx, query, id, lastErr); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -2,12 +2,21 @@ package tokens
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+
+	"github.com/passwordhash/jwt-test-task/internal/domain/models"
 	repoErr "github.com/passwordhash/jwt-test-task/internal/storage/errors"
 	"github.com/passwordhash/jwt-test-task/pkg/postgres"
 )
 
+// refreshTokenColumns are the columns read by scanRefreshToken.
+const refreshTokenColumns = `id, user_id, token_id, token_hash, user_agent, host(ip_address),
+	family_id, parent_id, is_revoked, used_at, successor, created_at, updated_at`
+
 type Storage struct {
 	db postgres.DB
 }
@@ -18,51 +27,469 @@ func New(db postgres.DB) *Storage {
 	}
 }
 
-func (s *Storage) Save(ctx context.Context, userID, tokenID, tokenHash, userAgent, ip string) (string, error) {
+// Save saves a new refresh token with the given id as a separate session.
+// If maxSessions is positive, the least recently refreshed sessions of the
+// user beyond it are revoked, whole families, in the same transaction. It
+// returns the access token IDs bound to the revoked tokens.
+func (s *Storage) Save(
+	ctx context.Context,
+	id, userID, tokenID, tokenHash, userAgent, ip string,
+	maxSessions int,
+) ([]string, error) {
 	const op = "storage.tokens.Save"
 
+	insertQuery := `
+	INSERT INTO refresh_tokens (id, user_id, token_id, token_hash, user_agent, ip_address)
+	VALUES ($1, $2, $3, $4, $5, $6);
+	`
+
+	evictQuery := `
+	UPDATE refresh_tokens
+	SET is_revoked = TRUE, updated_at = NOW()
+	WHERE user_id = $1 AND is_revoked = FALSE AND family_id IN (
+		SELECT family_id FROM refresh_tokens
+		WHERE user_id = $1 AND used_at IS NULL AND is_revoked = FALSE
+		ORDER BY created_at DESC, id DESC
+		OFFSET $2
+		FOR UPDATE
+	)
+	RETURNING token_id;
+	`
+
+	var evicted []string
+	err := postgres.WithTx(ctx, s.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, insertQuery, id, userID, tokenID, tokenHash, userAgent, ip)
+		if err != nil {
+			return err
+		}
+
+		if maxSessions <= 0 {
+			return nil
+		}
+
+		rows, err := tx.Query(ctx, evictQuery, userID, maxSessions)
+		if err != nil {
+			return err
+		}
+
+		evicted, err = pgx.CollectRows(rows, pgx.RowTo[string])
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return evicted, nil
+}
+
+// ByID returns the refresh token with the given id.
+func (s *Storage) ByID(ctx context.Context, id string) (models.RefreshToken, error) {
+	const op = "storage.tokens.ByID"
+
 	query := `
-	INSERT INTO refresh_tokens (user_id, token_id, token_hash, user_agent, ip_address)
-	VALUES ($1, $2, $3, $4, $5)
-	ON CONFLICT (user_id, user_agent) 
-	DO UPDATE SET 
-    		token_hash = EXCLUDED.token_hash,
-		ip_address = EXCLUDED.ip_address,
-    		updated_at = NOW(),
-		created_at = NOW(),
-    		is_revoked = FALSE
-	RETURNING id;
+	SELECT ` + refreshTokenColumns + `
+	FROM refresh_tokens
+	WHERE id = $1;
 	`
 
-	var id string
-	row := s.db.QueryRow(ctx, query, userID, tokenID, tokenHash, userAgent, ip)
-	err := row.Scan(&id)
+	t, err := scanRefreshToken(s.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, repoErr.ErrRefreshTokenNotFound)
+	}
 	if err != nil {
-		return "", fmt.Errorf("%s: %w", op, err)
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return id, nil
+	return t, nil
 }
 
+// ByTokenID returns the refresh token issued together with the access token
+// identified by tokenID.
+func (s *Storage) ByTokenID(ctx context.Context, tokenID string) (models.RefreshToken, error) {
+	const op = "storage.tokens.ByTokenID"
+
+	query := `
+	SELECT ` + refreshTokenColumns + `
+	FROM refresh_tokens
+	WHERE token_id = $1;
+	`
+
+	t, err := scanRefreshToken(s.db.QueryRow(ctx, query, tokenID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, repoErr.ErrRefreshTokenNotFound)
+	}
+	if err != nil {
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return t, nil
+}
+
+// Rotate marks the refresh token with the given id as used and saves its
+// successor with newID for the same user and user agent in a single
+// transaction. The successor joins the family of the rotated token. The
+// rotated record is locked first, so concurrent rotations of one token are
+// serialized and all but the first fail with ErrRefreshTokenUsed. The
+// sealed successor pair, if any, is stored on the rotated record. The
+// events are written to the webhook outbox in the same transaction.
+func (s *Storage) Rotate(
+	ctx context.Context,
+	id, newID, tokenID, tokenHash, ip string,
+	successor []byte,
+	events ...models.OutboxEvent,
+) error {
+	const op = "storage.tokens.Rotate"
+
+	lockQuery := `
+	SELECT user_id, user_agent, family_id, is_revoked, used_at IS NOT NULL
+	FROM refresh_tokens
+	WHERE id = $1
+	FOR UPDATE;
+	`
+
+	markUsedQuery := `
+	UPDATE refresh_tokens
+	SET used_at = NOW(), successor = $2, updated_at = NOW()
+	WHERE id = $1;
+	`
+
+	insertQuery := `
+	INSERT INTO refresh_tokens (id, user_id, token_id, token_hash, user_agent, ip_address, family_id, parent_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+	`
+
+	err := postgres.WithTx(ctx, s.db, func(tx pgx.Tx) error {
+		var (
+			userID, userAgent, familyID string
+			revoked, used               bool
+		)
+		err := tx.QueryRow(ctx, lockQuery, id).Scan(&userID, &userAgent, &familyID, &revoked, &used)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repoErr.ErrRefreshTokenNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if revoked || used {
+			return repoErr.ErrRefreshTokenUsed
+		}
+
+		if _, err := tx.Exec(ctx, markUsedQuery, id, successor); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, insertQuery, newID, userID, tokenID, tokenHash, userAgent, ip, familyID, id)
+		if err != nil {
+			return err
+		}
+
+		return saveEvents(ctx, tx, events)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Revoke revokes the refresh tokens of the user issued for the user agent.
+// It returns the access token IDs bound to the revoked tokens.
 func (s *Storage) Revoke(
 	ctx context.Context,
 	userID, userAgent string,
-) error {
+) ([]string, error) {
 	const op = "storage.tokens.Revoke"
 
 	query := `
 	UPDATE refresh_tokens
 	SET is_revoked = TRUE, updated_at = NOW()
-	WHERE user_id = $1 AND user_agent = $2 AND is_revoked = FALSE;
+	WHERE user_id = $1 AND user_agent = $2 AND is_revoked = FALSE
+	RETURNING token_id;
 	`
 
-	res, err := s.db.Exec(ctx, query, userID, userAgent)
+	tokenIDs, err := s.collectTokenIDs(ctx, query, userID, userAgent)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(tokenIDs) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, repoErr.ErrRefreshTokenNotFound)
+	}
+
+	return tokenIDs, nil
+}
+
+// RevokeAll revokes every refresh token of the user regardless of the user agent.
+// It returns the access token IDs bound to the revoked tokens.
+func (s *Storage) RevokeAll(ctx context.Context, userID string) ([]string, error) {
+	const op = "storage.tokens.RevokeAll"
+
+	query := `
+	UPDATE refresh_tokens
+	SET is_revoked = TRUE, updated_at = NOW()
+	WHERE user_id = $1 AND is_revoked = FALSE
+	RETURNING token_id;
+	`
+
+	tokenIDs, err := s.collectTokenIDs(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokenIDs, nil
+}
+
+func (s *Storage) collectTokenIDs(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
 	}
 
-	if res.RowsAffected() == 0 {
-		return fmt.Errorf("%s: %w", op, repoErr.ErrRefreshTokenNotFound)
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// RevokeFamily revokes every refresh token of the family. The events are
+// written to the webhook outbox in the same transaction. It returns the
+// access token IDs bound to the revoked tokens.
+func (s *Storage) RevokeFamily(ctx context.Context, familyID string, events ...models.OutboxEvent) ([]string, error) {
+	const op = "storage.tokens.RevokeFamily"
+
+	query := `
+	UPDATE refresh_tokens
+	SET is_revoked = TRUE, updated_at = NOW()
+	WHERE family_id = $1 AND is_revoked = FALSE
+	RETURNING token_id;
+	`
+
+	var tokenIDs []string
+	err := postgres.WithTx(ctx, s.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, familyID)
+		if err != nil {
+			return err
+		}
+
+		tokenIDs, err = pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return err
+		}
+
+		return saveEvents(ctx, tx, events)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokenIDs, nil
+}
+
+// RevokeByTokenID revokes the user's session the access token tokenID was
+// issued for. Every refresh token of its family is revoked, so the access
+// tokens issued before the last rotation are rejected as well. It returns
+// the access token IDs bound to the revoked tokens.
+func (s *Storage) RevokeByTokenID(ctx context.Context, userID, tokenID string) ([]string, error) {
+	const op = "storage.tokens.RevokeByTokenID"
+
+	query := `
+	UPDATE refresh_tokens
+	SET is_revoked = TRUE, updated_at = NOW()
+	WHERE user_id = $2 AND is_revoked = FALSE AND family_id = (
+		SELECT family_id FROM refresh_tokens WHERE token_id = $1 AND user_id = $2
+	)
+	RETURNING token_id;
+	`
+
+	tokenIDs, err := s.collectTokenIDs(ctx, query, tokenID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(tokenIDs) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, repoErr.ErrRefreshTokenNotFound)
+	}
+
+	return tokenIDs, nil
+}
+
+// RevokeSession revokes the user's session with the given family ID. Every
+// refresh token of the family is revoked, so the access tokens issued
+// before the last rotation are rejected as well. It returns the access
+// token IDs bound to the revoked tokens.
+func (s *Storage) RevokeSession(ctx context.Context, userID, familyID string) ([]string, error) {
+	const op = "storage.tokens.RevokeSession"
+
+	query := `
+	UPDATE refresh_tokens
+	SET is_revoked = TRUE, updated_at = NOW()
+	WHERE family_id = $1 AND user_id = $2 AND is_revoked = FALSE
+	RETURNING token_id;
+	`
+
+	tokenIDs, err := s.collectTokenIDs(ctx, query, familyID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(tokenIDs) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, repoErr.ErrRefreshTokenNotFound)
+	}
+
+	return tokenIDs, nil
+}
+
+// RevokeOthers revokes every session of the user except the one the access
+// token keepTokenID was issued for. Whole families are revoked, rotated
+// tokens included; a reused one is still detected as reuse, since it is
+// marked as used. It returns the access token IDs bound to the revoked
+// tokens and the number of revoked sessions.
+func (s *Storage) RevokeOthers(ctx context.Context, userID, keepTokenID string) ([]string, int, error) {
+	const op = "storage.tokens.RevokeOthers"
+
+	query := `
+	UPDATE refresh_tokens
+	SET is_revoked = TRUE, updated_at = NOW()
+	WHERE user_id = $1 AND is_revoked = FALSE AND family_id IS DISTINCT FROM (
+		SELECT family_id FROM refresh_tokens WHERE token_id = $2 AND user_id = $1
+	)
+	RETURNING token_id, used_at IS NULL;
+	`
+
+	rows, err := s.db.Query(ctx, query, userID, keepTokenID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Every session has exactly one token that is not rotated yet.
+	sessions := 0
+	tokenIDs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (string, error) {
+		var (
+			tokenID string
+			active  bool
+		)
+		if err := row.Scan(&tokenID, &active); err != nil {
+			return "", err
+		}
+
+		if active {
+			sessions++
+		}
+
+		return tokenID, nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokenIDs, sessions, nil
+}
+
+// ByUserID returns every refresh token of the user, including used and
+// revoked ones, newest first.
+func (s *Storage) ByUserID(ctx context.Context, userID string) ([]models.RefreshToken, error) {
+	const op = "storage.tokens.ByUserID"
+
+	query := `
+	SELECT ` + refreshTokenColumns + `
+	FROM refresh_tokens
+	WHERE user_id = $1
+	ORDER BY created_at DESC;
+	`
+
+	tokens, err := s.collectTokens(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+// SessionsByUserID returns the active sessions of the user, most recently
+// refreshed first. A session is a token family with a token that is neither
+// revoked nor rotated; it is reported with the user agent and IP address of
+// that token and the creation time of the oldest token of the family.
+func (s *Storage) SessionsByUserID(ctx context.Context, userID string) ([]models.Session, error) {
+	const op = "storage.tokens.SessionsByUserID"
+
+	query := `
+	SELECT t.family_id, t.user_agent, host(t.ip_address),
+		(SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = t.family_id),
+		t.created_at
+	FROM refresh_tokens t
+	WHERE t.user_id = $1 AND t.is_revoked = FALSE AND t.used_at IS NULL
+	ORDER BY t.created_at DESC;
+	`
+
+	rows, err := s.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sessions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Session, error) {
+		var session models.Session
+		err := row.Scan(&session.ID, &session.UserAgent, &session.IP, &session.CreatedAt, &session.LastUsedAt)
+
+		return session, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+// Purge deletes the refresh tokens that can no longer be used: revoked and
+// rotated ones last updated more than keepFor ago, and, if idleFor is not
+// zero, active ones not refreshed for idleFor. It returns the number of
+// deleted rows.
+func (s *Storage) Purge(ctx context.Context, keepFor, idleFor time.Duration) (int64, error) {
+	const op = "storage.tokens.Purge"
+
+	query := `
+	DELETE FROM refresh_tokens
+	WHERE ((is_revoked OR used_at IS NOT NULL)
+			AND updated_at <= NOW() - make_interval(secs => $1))
+		OR ($2::float8 > 0 AND updated_at <= NOW() - make_interval(secs => $2));
+	`
+
+	tag, err := s.db.Exec(ctx, query, keepFor.Seconds(), idleFor.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (s *Storage) collectTokens(ctx context.Context, query string, args ...any) ([]models.RefreshToken, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.RefreshToken, error) {
+		return scanRefreshToken(row)
+	})
+}
+
+func scanRefreshToken(row pgx.Row) (models.RefreshToken, error) {
+	var t models.RefreshToken
+	err := row.Scan(
+		&t.ID, &t.UserID, &t.TokenID, &t.TokenHash, &t.UserAgent, &t.IP,
+		&t.FamilyID, &t.ParentID, &t.IsRevoked, &t.UsedAt, &t.Successor, &t.CreatedAt, &t.UpdatedAt,
+	)
+
+	return t, err
+}
+
+func saveEvents(ctx context.Context, tx pgx.Tx, events []models.OutboxEvent) error {
+	query := `
+	INSERT INTO webhook_outbox (event_type, payload)
+	VALUES ($1, $2);
+	`
+
+	for _, e := range events {
+		if _, err := tx.Exec(ctx, query, e.Type, e.Payload); err != nil {
+			return err
+		}
 	}
 
 	return nil
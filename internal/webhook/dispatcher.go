@@ -0,0 +1,203 @@
+package webhook
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/passwordhash/jwt-test-task/internal/config"
+	"github.com/passwordhash/jwt-test-task/internal/domain/models"
+)
+
+// claimLease is how long a claimed event stays invisible to other
+// dispatchers. It must be longer than a single delivery attempt.
+const claimLease = time.Minute
+
+type OutboxStorage interface {
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error)
+	MarkDelivered(ctx context.Context, id string) error
+	MarkFailed(ctx context.Context, id string, retryIn time.Duration, lastErr string) error
+	MarkDead(ctx context.Context, id string, lastErr string) error
+}
+
+type Sender interface {
+	Send(ctx context.Context, event models.OutboxEvent) error
+}
+
+// Dispatcher periodically delivers pending outbox events. Failed deliveries
+// are retried with exponential backoff until MaxAttempts is reached, after
+// which the event is moved to the dead-letter state.
+type Dispatcher struct {
+	log     *slog.Logger
+	storage OutboxStorage
+	sender  Sender
+
+	pollInterval time.Duration
+	batchSize    int
+	maxAttempts  int
+	baseBackoff  time.Duration
+	maxBackoff   time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func NewDispatcher(
+	log *slog.Logger,
+	cfg config.WebhookConfig,
+	storage OutboxStorage,
+	sender Sender,
+) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Dispatcher{
+		log:     log,
+		storage: storage,
+		sender:  sender,
+
+		pollInterval: cfg.PollInterval,
+		batchSize:    cfg.BatchSize,
+		maxAttempts:  cfg.MaxAttempts,
+		baseBackoff:  cfg.BaseBackoff,
+		maxBackoff:   cfg.MaxBackoff,
+
+		ctx:    ctx,
+		cancel: cancel,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Start runs the dispatch loop in the background.
+func (d *Dispatcher) Start() {
+	const op = "webhook.Dispatcher.Start"
+
+	d.log.Info("Starting webhook dispatcher", slog.String("op", op))
+
+	go d.run()
+}
+
+// Stop stops the dispatch loop and waits for the in-flight batch. If ctx is
+// done first, the in-flight deliveries are cancelled.
+func (d *Dispatcher) Stop(ctx context.Context) {
+	const op = "webhook.Dispatcher.Stop"
+
+	log := d.log.With(slog.String("op", op))
+
+	log.Info("Stopping webhook dispatcher")
+
+	close(d.stop)
+
+	select {
+	case <-d.done:
+		log.Info("Webhook dispatcher stopped gracefully")
+	case <-ctx.Done():
+		d.cancel()
+		<-d.done
+		log.Warn("Webhook dispatcher stopped with in-flight deliveries cancelled")
+	}
+
+	d.cancel()
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.dispatch()
+
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch() {
+	const op = "webhook.Dispatcher.dispatch"
+
+	log := d.log.With(slog.String("op", op))
+
+	events, err := d.storage.Claim(d.ctx, d.batchSize, claimLease)
+	if err != nil {
+		log.Error("failed to claim outbox events", slog.Any("error", err))
+
+		return
+	}
+
+	for _, event := range events {
+		select {
+		case <-d.stop:
+			// Unsent events become available again once the lease expires.
+			return
+		default:
+		}
+
+		d.deliver(log, event)
+	}
+}
+
+func (d *Dispatcher) deliver(log *slog.Logger, event models.OutboxEvent) {
+	log = log.With(
+		slog.String("eventID", event.ID),
+		slog.String("eventType", event.Type),
+		slog.Int("attempt", event.Attempts+1),
+	)
+
+	sendErr := d.sender.Send(d.ctx, event)
+	if sendErr == nil {
+		if err := d.storage.MarkDelivered(d.ctx, event.ID); err != nil {
+			log.Error("failed to mark event delivered", slog.Any("error", err))
+
+			return
+		}
+
+		log.Info("webhook event delivered")
+
+		return
+	}
+
+	if event.Attempts+1 >= d.maxAttempts {
+		if err := d.storage.MarkDead(d.ctx, event.ID, sendErr.Error()); err != nil {
+			log.Error("failed to move event to dead letter", slog.Any("error", err))
+
+			return
+		}
+
+		log.Error("webhook event moved to dead letter", slog.Any("error", sendErr))
+
+		return
+	}
+
+	retryIn := d.backoff(event.Attempts)
+	if err := d.storage.MarkFailed(d.ctx, event.ID, retryIn, sendErr.Error()); err != nil {
+		log.Error("failed to reschedule event", slog.Any("error", err))
+
+		return
+	}
+
+	log.Warn("webhook delivery failed, retry scheduled",
+		slog.Duration("retryIn", retryIn),
+		slog.Any("error", sendErr),
+	)
+}
+
+// backoff returns the delay before the next attempt: baseBackoff doubled for
+// every previous attempt, capped by maxBackoff.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.baseBackoff
+	for i := 0; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.maxBackoff {
+			return d.maxBackoff
+		}
+	}
+
+	return delay
+}
@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/passwordhash/jwt-test-task/internal/domain/models"
+	webhookPkg "github.com/passwordhash/jwt-test-task/pkg/webhook"
+)
+
+type envelope struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Notifier delivers events to a webhook URL with a POST request. Requests
+// are signed with the shared secret, see pkg/webhook.
+type Notifier struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+func New(url, secret string, timeout time.Duration) *Notifier {
+	return &Notifier{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: timeout}, //nolint:exhaustruct
+	}
+}
+
+// Send posts the event to the webhook. Any non-2xx response is treated as
+// a failed delivery.
+func (n *Notifier) Send(ctx context.Context, event models.OutboxEvent) error {
+	const op = "webhook.Send"
+
+	body, err := json.Marshal(envelope{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt.UTC(),
+		Data:      event.Payload,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	webhookPkg.SetHeaders(req.Header, n.secret, event.ID, time.Now(), body)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: unexpected status code %d", op, resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/passwordhash/jwt-test-task/internal/domain/models"
+	webhookPkg "github.com/passwordhash/jwt-test-task/pkg/webhook"
+)
+
+const testSecret = "webhook-secret"
+
+func testEvent() models.OutboxEvent {
+	return models.OutboxEvent{
+		ID:        "evt-1",
+		Type:      models.EventTypeNewIP,
+		Payload:   []byte(`{"user_id":"u-1"}`),
+		CreatedAt: time.Now(),
+	}
+}
+
+func TestNotifierSendSignsRequest(t *testing.T) {
+	var (
+		gotBody   []byte
+		verifyErr error
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		verifyErr = webhookPkg.NewVerifier([]byte(testSecret)).Verify(r.Header, gotBody)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	event := testEvent()
+	if err := New(srv.URL, testSecret, time.Second).Send(context.Background(), event); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if verifyErr != nil {
+		t.Fatalf("signature verification failed: %v", verifyErr)
+	}
+
+	var env envelope
+	if err := json.Unmarshal(gotBody, &env); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if env.ID != event.ID || env.Type != event.Type || string(env.Data) != string(event.Payload) {
+		t.Errorf("unexpected envelope %+v", env)
+	}
+}
+
+func TestNotifierSendWrongSecret(t *testing.T) {
+	var verifyErr error
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verifyErr = webhookPkg.NewVerifier([]byte("other-secret")).Verify(r.Header, body)
+	}))
+	defer srv.Close()
+
+	if err := New(srv.URL, testSecret, time.Second).Send(context.Background(), testEvent()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if !errors.Is(verifyErr, webhookPkg.ErrInvalidSignature) {
+		t.Errorf("Verify() error = %v, want %v", verifyErr, webhookPkg.ErrInvalidSignature)
+	}
+}
+
+func TestNotifierSendStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "accepted", status: http.StatusAccepted},
+		{name: "redirect", status: http.StatusNotModified, wantErr: true},
+		{name: "client error", status: http.StatusBadRequest, wantErr: true},
+		{name: "server error", status: http.StatusInternalServerError, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			err := New(srv.URL, testSecret, time.Second).Send(context.Background(), testEvent())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNotifierSendTimeout(t *testing.T) {
+	release := make(chan struct{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	start := time.Now()
+	err := New(srv.URL, testSecret, 50*time.Millisecond).Send(context.Background(), testEvent())
+	if err == nil {
+		t.Fatal("Send() error = nil, want timeout")
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Send() returned after %s, want about the 50ms timeout", elapsed)
+	}
+}
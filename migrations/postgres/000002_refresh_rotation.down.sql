@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_active_user_agent;
+
+-- Only one row per (user_id, user_agent) fits the restored constraint, so
+-- the newest row of each pair is kept and the older, rotated ones are lost.
+DELETE FROM refresh_tokens t
+USING refresh_tokens newer
+WHERE t.user_id = newer.user_id AND t.user_agent = newer.user_agent
+    AND (t.created_at, t.id) < (newer.created_at, newer.id);
+
+ALTER TABLE refresh_tokens ADD CONSTRAINT refresh_tokens_user_id_user_agent_key UNIQUE (user_id, user_agent);
+
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS used_at;
@@ -0,0 +1,6 @@
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS used_at TIMESTAMP;
+
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS refresh_tokens_user_id_user_agent_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_active_user_agent
+    ON refresh_tokens(user_id, user_agent) WHERE used_at IS NULL;
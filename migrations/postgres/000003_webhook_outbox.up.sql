@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP,
+
+    CHECK (status IN ('pending', 'delivered', 'dead'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_pending ON webhook_outbox(next_attempt_at) WHERE status = 'pending';
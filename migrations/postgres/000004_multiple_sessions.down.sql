@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_active_user_id;
+
+DELETE FROM refresh_tokens t
+USING refresh_tokens newer
+WHERE t.used_at IS NULL AND newer.used_at IS NULL
+    AND t.user_id = newer.user_id AND t.user_agent = newer.user_agent
+    AND (t.created_at, t.id) < (newer.created_at, newer.id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_active_user_agent
+    ON refresh_tokens(user_id, user_agent) WHERE used_at IS NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_active_user_agent;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_active_user_id
+    ON refresh_tokens(user_id, created_at) WHERE used_at IS NULL AND is_revoked = FALSE;
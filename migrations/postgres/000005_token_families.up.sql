@@ -0,0 +1,10 @@
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS family_id UUID;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES refresh_tokens(id) ON DELETE SET NULL;
+
+-- The lineage of already rotated tokens is unknown, every row starts its own family.
+UPDATE refresh_tokens SET family_id = id WHERE family_id IS NULL;
+
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET DEFAULT uuid_generate_v4();
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
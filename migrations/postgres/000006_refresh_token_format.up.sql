@@ -0,0 +1,3 @@
+-- Refresh tokens are looked up by the session ID they carry, the hash of
+-- their secret doesn't need to be unique.
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS refresh_tokens_token_hash_key;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_access_token_revoked ON revoked_access_tokens;
+DROP FUNCTION IF EXISTS notify_access_token_revoked();
+DROP TABLE IF EXISTS revoked_access_tokens;
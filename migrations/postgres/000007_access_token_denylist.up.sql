@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS revoked_access_tokens (
+    token_id UUID PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_access_tokens_expires_at ON revoked_access_tokens(expires_at);
+
+-- Every instance keeps the denylist in memory and learns about new entries
+-- through this channel. The payload is "<token_id> <seconds until expiry>".
+CREATE OR REPLACE FUNCTION notify_access_token_revoked() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('access_token_revoked',
+        NEW.token_id::text || ' ' || GREATEST(CEIL(EXTRACT(EPOCH FROM NEW.expires_at - NOW())), 0)::bigint);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_access_token_revoked ON revoked_access_tokens;
+CREATE TRIGGER trg_access_token_revoked
+    AFTER INSERT ON revoked_access_tokens
+    FOR EACH ROW EXECUTE FUNCTION notify_access_token_revoked();
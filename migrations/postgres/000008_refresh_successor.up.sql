@@ -0,0 +1,3 @@
+-- The pair a token was rotated to, sealed with a key derived from the rotated
+-- token's secret, so every instance can return it within the grace period.
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS successor BYTEA;
@@ -0,0 +1,56 @@
+// Package clock abstracts the current time, so time dependent code can be
+// driven by a fake clock.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+}
+
+var _ Clock = System{}
+
+// System is the real wall clock.
+type System struct{}
+
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+var _ Clock = (*Fake)(nil)
+
+// Fake is a clock that only moves when told to. It is safe for concurrent use.
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+// Set moves the clock to t.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = t
+}
+
+// Advance moves the clock forward by d.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = f.now.Add(d)
+}
@@ -0,0 +1,50 @@
+package hasher
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+var _ Hasher = Argon2idHasher{}
+
+// Argon2idHasher hashes secrets with argon2id. Memory is in KiB.
+type Argon2idHasher struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+	KeyLen  uint32
+}
+
+func (h Argon2idHasher) Hash(secret string) (string, error) {
+	if h.Time < 1 || h.Threads < 1 || h.KeyLen < 1 {
+		return "", fmt.Errorf("argon2id time, threads and key length must be positive")
+	}
+
+	salt, err := newSalt()
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(secret), salt, h.Time, h.Memory, h.Threads, h.KeyLen)
+
+	return encode(Argon2id, fmt.Sprintf("v=%d$%s", argon2.Version, h.params()), salt, key), nil
+}
+
+func (h Argon2idHasher) params() string {
+	return fmt.Sprintf("m=%d,t=%d,p=%d", h.Memory, h.Time, h.Threads)
+}
+
+func compareArgon2id(secret, hash string) error {
+	params, salt, key, err := decode(hash)
+	if err != nil {
+		return err
+	}
+
+	var h Argon2idHasher
+	if _, err := fmt.Sscanf(params, "m=%d,t=%d,p=%d", &h.Memory, &h.Time, &h.Threads); err != nil {
+		return ErrMalformedHash
+	}
+
+	return compareKeys(key, argon2.IDKey([]byte(secret), salt, h.Time, h.Memory, h.Threads, uint32(len(key))))
+}
@@ -0,0 +1,36 @@
+package hasher
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var _ Hasher = BcryptHasher{}
+
+// BcryptHasher hashes secrets with bcrypt. Secrets longer than 72 bytes
+// are rejected.
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Hash(secret string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), h.Cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func compareBcrypt(secret, hash string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(secret))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatch
+	}
+	if err != nil {
+		return ErrMalformedHash
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+// Package hasher hashes secrets with bcrypt, argon2id or scrypt. Encoded
+// hashes carry their algorithm and parameters, so Compare verifies a hash
+// made by any of them, whatever the current hasher is.
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrMismatch         = errors.New("secret doesn't match the hash")
+	ErrMalformedHash    = errors.New("malformed hash")
+	ErrUnknownAlgorithm = errors.New("unknown hash algorithm")
+)
+
+type Algorithm string
+
+const (
+	Bcrypt   Algorithm = "bcrypt"
+	Argon2id Algorithm = "argon2id"
+	Scrypt   Algorithm = "scrypt"
+)
+
+const saltSize = 16
+
+// Hasher hashes secrets with a single algorithm and parameter set.
+type Hasher interface {
+	// Hash returns the encoded hash of the secret.
+	Hash(secret string) (string, error)
+}
+
+// Compare checks the secret against a hash made by any of the hashers.
+func Compare(secret, hash string) error {
+	alg, err := Identify(hash)
+	if err != nil {
+		return err
+	}
+
+	switch alg {
+	case Bcrypt:
+		return compareBcrypt(secret, hash)
+	case Argon2id:
+		return compareArgon2id(secret, hash)
+	default:
+		return compareScrypt(secret, hash)
+	}
+}
+
+// Identify returns the algorithm of the hash by its prefix.
+func Identify(hash string) (Algorithm, error) {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return Bcrypt, nil
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return Argon2id, nil
+	case strings.HasPrefix(hash, "$scrypt$"):
+		return Scrypt, nil
+	default:
+		return "", ErrUnknownAlgorithm
+	}
+}
+
+func newSalt() ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	return salt, nil
+}
+
+// encode returns a hash in the PHC string format:
+// $<alg>$<params>$<salt>$<key>.
+func encode(alg Algorithm, params string, salt, key []byte) string {
+	return fmt.Sprintf("$%s$%s$%s$%s", alg, params,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+// decode splits a PHC string made by encode. The params part is returned
+// as is, without the algorithm version if there is one.
+func decode(hash string) (params string, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	// Argon2 hashes have an extra version part: $argon2id$v=19$...
+	if len(parts) == 6 {
+		parts = append(parts[:2], parts[3:]...)
+	}
+	if len(parts) != 5 {
+		return "", nil, nil, ErrMalformedHash
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return "", nil, nil, ErrMalformedHash
+	}
+
+	key, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(key) == 0 {
+		return "", nil, nil, ErrMalformedHash
+	}
+
+	return parts[2], salt, key, nil
+}
+
+func compareKeys(a, b []byte) error {
+	if subtle.ConstantTimeCompare(a, b) != 1 {
+		return ErrMismatch
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package hasher_test
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/passwordhash/jwt-test-task/pkg/hasher"
+)
+
+const testSecret = "c2VjcmV0LXBhcnQtb2YtdGhlLXJlZnJlc2gtdG9rZW4"
+
+// testHashers use cheap parameters, so the tests stay fast.
+var testHashers = []struct {
+	alg    hasher.Algorithm
+	hasher hasher.Hasher
+}{
+	{alg: hasher.Bcrypt, hasher: hasher.BcryptHasher{Cost: bcrypt.MinCost}},
+	{alg: hasher.Argon2id, hasher: hasher.Argon2idHasher{Time: 1, Memory: 64, Threads: 1, KeyLen: 32}},
+	{alg: hasher.Scrypt, hasher: hasher.ScryptHasher{N: 1024, R: 8, P: 1, KeyLen: 32}},
+}
+
+// defaultHashers are the hashers with the defaults of config.RefreshHashConfig.
+var defaultHashers = []struct {
+	alg    hasher.Algorithm
+	hasher hasher.Hasher
+}{
+	{alg: hasher.Bcrypt, hasher: hasher.BcryptHasher{Cost: 10}},
+	{alg: hasher.Argon2id, hasher: hasher.Argon2idHasher{Time: 1, Memory: 19456, Threads: 1, KeyLen: 32}},
+	{alg: hasher.Scrypt, hasher: hasher.ScryptHasher{N: 32768, R: 8, P: 1, KeyLen: 32}},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, tt := range testHashers {
+		t.Run(string(tt.alg), func(t *testing.T) {
+			hash, err := tt.hasher.Hash(testSecret)
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+
+			alg, err := hasher.Identify(hash)
+			if err != nil {
+				t.Fatalf("Identify() error = %v", err)
+			}
+			if alg != tt.alg {
+				t.Errorf("Identify() = %q, want %q", alg, tt.alg)
+			}
+
+			if err := hasher.Compare(testSecret, hash); err != nil {
+				t.Errorf("Compare() error = %v", err)
+			}
+
+			if err := hasher.Compare(testSecret+"x", hash); !errors.Is(err, hasher.ErrMismatch) {
+				t.Errorf("Compare() with another secret error = %v, want %v", err, hasher.ErrMismatch)
+			}
+
+			again, err := tt.hasher.Hash(testSecret)
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+			if again == hash {
+				t.Error("Hash() returned the same hash twice, the salt is not random")
+			}
+		})
+	}
+}
+
+func TestCompareInvalidHash(t *testing.T) {
+	tests := []struct {
+		name    string
+		hash    string
+		wantErr error
+	}{
+		{name: "empty", hash: "", wantErr: hasher.ErrUnknownAlgorithm},
+		{name: "plain text", hash: testSecret, wantErr: hasher.ErrUnknownAlgorithm},
+		{name: "unknown algorithm", hash: "$pbkdf2$i=1000$c2FsdA$a2V5", wantErr: hasher.ErrUnknownAlgorithm},
+		{name: "truncated bcrypt", hash: "$2a$10$abc", wantErr: hasher.ErrMalformedHash},
+		{name: "argon2id without key", hash: "$argon2id$v=19$m=64,t=1,p=1$c2FsdA", wantErr: hasher.ErrMalformedHash},
+		{name: "argon2id bad params", hash: "$argon2id$v=19$x$c2FsdA$a2V5", wantErr: hasher.ErrMalformedHash},
+		{name: "scrypt bad salt", hash: "$scrypt$ln=10,r=8,p=1$!!!$a2V5", wantErr: hasher.ErrMalformedHash},
+		{name: "scrypt huge N", hash: "$scrypt$ln=31,r=8,p=1$c2FsdA$a2V5", wantErr: hasher.ErrMalformedHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := hasher.Compare(testSecret, tt.hash); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Compare() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHashInvalidParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		hasher hasher.Hasher
+	}{
+		{name: "bcrypt cost too high", hasher: hasher.BcryptHasher{Cost: bcrypt.MaxCost + 1}},
+		{name: "argon2id zero time", hasher: hasher.Argon2idHasher{Time: 0, Memory: 64, Threads: 1, KeyLen: 32}},
+		{name: "scrypt N not a power of two", hasher: hasher.ScryptHasher{N: 1000, R: 8, P: 1, KeyLen: 32}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.hasher.Hash(testSecret); err == nil {
+				t.Error("Hash() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func BenchmarkHash(b *testing.B) {
+	for _, bb := range defaultHashers {
+		b.Run(string(bb.alg), func(b *testing.B) {
+			for b.Loop() {
+				if _, err := bb.hasher.Hash(testSecret); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCompare(b *testing.B) {
+	for _, bb := range defaultHashers {
+		b.Run(string(bb.alg), func(b *testing.B) {
+			hash, err := bb.hasher.Hash(testSecret)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			for b.Loop() {
+				if err := hasher.Compare(testSecret, hash); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package hasher
+
+import (
+	"fmt"
+	"math/bits"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+var _ Hasher = ScryptHasher{}
+
+// ScryptHasher hashes secrets with scrypt. N must be a power of two.
+type ScryptHasher struct {
+	N      int
+	R      int
+	P      int
+	KeyLen int
+}
+
+func (h ScryptHasher) Hash(secret string) (string, error) {
+	if h.N < 2 || h.N&(h.N-1) != 0 {
+		return "", fmt.Errorf("scrypt N must be a power of two, got %d", h.N)
+	}
+
+	salt, err := newSalt()
+	if err != nil {
+		return "", err
+	}
+
+	key, err := scrypt.Key([]byte(secret), salt, h.N, h.R, h.P, h.KeyLen)
+	if err != nil {
+		return "", err
+	}
+
+	return encode(Scrypt, h.params(), salt, key), nil
+}
+
+// params encodes N as its base 2 logarithm, as the PHC scrypt format does.
+func (h ScryptHasher) params() string {
+	return fmt.Sprintf("ln=%d,r=%d,p=%d", bits.Len(uint(h.N))-1, h.R, h.P)
+}
+
+func compareScrypt(secret, hash string) error {
+	params, salt, key, err := decode(hash)
+	if err != nil {
+		return err
+	}
+
+	var ln, r, p int
+	if _, err := fmt.Sscanf(params, "ln=%d,r=%d,p=%d", &ln, &r, &p); err != nil || ln < 1 || ln > 30 {
+		return ErrMalformedHash
+	}
+
+	computed, err := scrypt.Key([]byte(secret), salt, 1<<ln, r, p, len(key))
+	if err != nil {
+		return ErrMalformedHash
+	}
+
+	return compareKeys(key, computed)
+}
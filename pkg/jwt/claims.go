@@ -0,0 +1,118 @@
+package jwt
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// Claims is implemented by claim structs that embed RegisteredClaims.
+type Claims interface {
+	Registered() RegisteredClaims
+}
+
+// RegisteredClaims are the registered claims of RFC 7519. Embed it into a
+// struct to add private claims:
+//
+//	type AccessClaims struct {
+//		jwt.RegisteredClaims
+//		TokenID string `json:"token_id"`
+//	}
+type RegisteredClaims struct {
+	Issuer    string       `json:"iss,omitempty"`
+	Subject   string       `json:"sub,omitempty"`
+	Audience  Audience     `json:"aud,omitempty"`
+	ExpiresAt *NumericDate `json:"exp,omitempty"`
+	NotBefore *NumericDate `json:"nbf,omitempty"`
+	IssuedAt  *NumericDate `json:"iat,omitempty"`
+	ID        string       `json:"jti,omitempty"`
+}
+
+func (c RegisteredClaims) Registered() RegisteredClaims {
+	return c
+}
+
+// NumericDate is a time encoded as a JSON number of seconds since the Unix
+// epoch. Fractional values are accepted when decoding, as allowed by RFC 7519.
+type NumericDate struct {
+	time.Time
+}
+
+func NewNumericDate(t time.Time) *NumericDate {
+	return &NumericDate{Time: t.Truncate(time.Second)}
+}
+
+func (d NumericDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Unix())
+}
+
+func (d *NumericDate) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err != nil {
+		return &Err{reason: "numeric date has invalid type", err: ErrParseToken}
+	}
+
+	sec, frac := math.Modf(seconds)
+	d.Time = time.Unix(int64(sec), int64(frac*1e9))
+
+	return nil
+}
+
+// Audience is the aud claim. It is a single string or an array of strings
+// in JSON; a single value is encoded as a string.
+type Audience []string
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*a = nil
+
+		return nil
+	}
+
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return &Err{reason: "aud claim has invalid type", err: ErrParseToken}
+	}
+
+	*a = multiple
+
+	return nil
+}
+
+// NewTokenFrom creates a token with the typed claims signed by the key.
+// Unlike NewToken, the claims are signed as is, so the caller sets the time
+// claims.
+func NewTokenFrom[T Claims](claims T, key SigningKey) (string, error) {
+	return newToken(claims, key)
+}
+
+// ParseInto verifies the token with the parser and decodes its claims into T.
+func ParseInto[T Claims](p *Parser, token string, keys KeySet) (T, error) {
+	var claims T
+
+	decodedPayload, err := p.verify(token, keys)
+	if err != nil {
+		return claims, err
+	}
+
+	if err := json.Unmarshal(decodedPayload, &claims); err != nil {
+		return claims, &Err{reason: err.Error(), err: ErrParseToken}
+	}
+
+	return claims, nil
+}
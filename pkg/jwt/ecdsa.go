@@ -0,0 +1,120 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+)
+
+var (
+	_ SigningKey   = (*ECDSASigningKey)(nil)
+	_ VerifyingKey = (*ECDSAVerifyingKey)(nil)
+)
+
+// ECDSASigningKey signs tokens with ES256 (P-256), ES384 (P-384) or
+// ES512 (P-521). Signatures are encoded as R || S as required by RFC 7518.
+type ECDSASigningKey struct {
+	alg Alg
+	key *ecdsa.PrivateKey
+}
+
+func NewECDSASigningKey(alg Alg, key *ecdsa.PrivateKey) (*ECDSASigningKey, error) {
+	if key == nil {
+		return nil, &Err{reason: "nil ECDSA key", err: ErrInvalidKey}
+	}
+
+	if err := checkECDSA(alg, &key.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return &ECDSASigningKey{alg: alg, key: key}, nil
+}
+
+func (k *ECDSASigningKey) Alg() Alg {
+	return k.alg
+}
+
+func (k *ECDSASigningKey) Sign(signingInput []byte) ([]byte, error) {
+	h, _ := hashFor(k.alg)
+
+	r, s, err := ecdsa.Sign(rand.Reader, k.key, digest(h, signingInput))
+	if err != nil {
+		return nil, err
+	}
+
+	size := curveByteSize(k.key.Curve)
+	sig := make([]byte, 2*size)
+	r.FillBytes(sig[:size])
+	s.FillBytes(sig[size:])
+
+	return sig, nil
+}
+
+func (k *ECDSASigningKey) Public() VerifyingKey {
+	return &ECDSAVerifyingKey{alg: k.alg, key: &k.key.PublicKey}
+}
+
+// ECDSAVerifyingKey verifies ES256, ES384 or ES512 signatures.
+type ECDSAVerifyingKey struct {
+	alg Alg
+	key *ecdsa.PublicKey
+}
+
+func NewECDSAVerifyingKey(alg Alg, key *ecdsa.PublicKey) (*ECDSAVerifyingKey, error) {
+	if err := checkECDSA(alg, key); err != nil {
+		return nil, err
+	}
+
+	return &ECDSAVerifyingKey{alg: alg, key: key}, nil
+}
+
+func (k *ECDSAVerifyingKey) Alg() Alg {
+	return k.alg
+}
+
+func (k *ECDSAVerifyingKey) Verify(signingInput, signature []byte) error {
+	size := curveByteSize(k.key.Curve)
+	if len(signature) != 2*size {
+		return errInvalidSignature()
+	}
+
+	h, _ := hashFor(k.alg)
+
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+
+	if !ecdsa.Verify(k.key, digest(h, signingInput), r, s) {
+		return errInvalidSignature()
+	}
+
+	return nil
+}
+
+func checkECDSA(alg Alg, key *ecdsa.PublicKey) error {
+	if key == nil || key.Curve == nil {
+		return &Err{reason: "nil ECDSA key", err: ErrInvalidKey}
+	}
+
+	var curve elliptic.Curve
+	switch alg {
+	case ES256:
+		curve = elliptic.P256()
+	case ES384:
+		curve = elliptic.P384()
+	case ES512:
+		curve = elliptic.P521()
+	default:
+		return &Err{reason: "algorithm " + string(alg) + " is not an ECDSA algorithm", err: ErrAlgMismatch}
+	}
+
+	if key.Curve != curve {
+		return &Err{reason: "curve " + key.Curve.Params().Name + " doesn't match algorithm " + string(alg), err: ErrAlgMismatch}
+	}
+
+	return nil
+}
+
+func curveByteSize(curve elliptic.Curve) int {
+	return (curve.Params().BitSize + 7) / 8
+}
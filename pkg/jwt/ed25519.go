@@ -0,0 +1,68 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+)
+
+var (
+	_ SigningKey   = (*Ed25519SigningKey)(nil)
+	_ VerifyingKey = (*Ed25519VerifyingKey)(nil)
+)
+
+// Ed25519SigningKey signs tokens with the EdDSA algorithm over Ed25519.
+type Ed25519SigningKey struct {
+	key ed25519.PrivateKey
+}
+
+func NewEd25519SigningKey(alg Alg, key ed25519.PrivateKey) (*Ed25519SigningKey, error) {
+	if alg != EdDSA {
+		return nil, &Err{reason: "algorithm " + string(alg) + " is not an EdDSA algorithm", err: ErrAlgMismatch}
+	}
+
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, &Err{reason: "invalid Ed25519 private key size", err: ErrInvalidKey}
+	}
+
+	return &Ed25519SigningKey{key: key}, nil
+}
+
+func (k *Ed25519SigningKey) Alg() Alg {
+	return EdDSA
+}
+
+func (k *Ed25519SigningKey) Sign(signingInput []byte) ([]byte, error) {
+	return ed25519.Sign(k.key, signingInput), nil
+}
+
+func (k *Ed25519SigningKey) Public() VerifyingKey {
+	return &Ed25519VerifyingKey{key: k.key.Public().(ed25519.PublicKey)}
+}
+
+// Ed25519VerifyingKey verifies EdDSA signatures over Ed25519.
+type Ed25519VerifyingKey struct {
+	key ed25519.PublicKey
+}
+
+func NewEd25519VerifyingKey(alg Alg, key ed25519.PublicKey) (*Ed25519VerifyingKey, error) {
+	if alg != EdDSA {
+		return nil, &Err{reason: "algorithm " + string(alg) + " is not an EdDSA algorithm", err: ErrAlgMismatch}
+	}
+
+	if len(key) != ed25519.PublicKeySize {
+		return nil, &Err{reason: "invalid Ed25519 public key size", err: ErrInvalidKey}
+	}
+
+	return &Ed25519VerifyingKey{key: key}, nil
+}
+
+func (k *Ed25519VerifyingKey) Alg() Alg {
+	return EdDSA
+}
+
+func (k *Ed25519VerifyingKey) Verify(signingInput, signature []byte) error {
+	if !ed25519.Verify(k.key, signingInput, signature) {
+		return errInvalidSignature()
+	}
+
+	return nil
+}
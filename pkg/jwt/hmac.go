@@ -0,0 +1,61 @@
+package jwt
+
+import (
+	"crypto/hmac"
+)
+
+var (
+	_ SigningKey   = (*HMACKey)(nil)
+	_ VerifyingKey = (*HMACKey)(nil)
+)
+
+// HMACKey is a shared secret for the HS256, HS384 and HS512 algorithms.
+// It both signs and verifies tokens.
+type HMACKey struct {
+	alg    Alg
+	secret []byte
+}
+
+func NewHMACKey(alg Alg, secret []byte) (*HMACKey, error) {
+	switch alg {
+	case HS256, HS384, HS512:
+	default:
+		return nil, &Err{reason: "algorithm " + string(alg) + " is not an HMAC algorithm", err: ErrAlgMismatch}
+	}
+
+	if len(secret) == 0 {
+		return nil, &Err{reason: "empty HMAC secret", err: ErrInvalidKey}
+	}
+
+	return &HMACKey{alg: alg, secret: secret}, nil
+}
+
+func (k *HMACKey) Alg() Alg {
+	return k.alg
+}
+
+func (k *HMACKey) Sign(signingInput []byte) ([]byte, error) {
+	h, _ := hashFor(k.alg)
+
+	mac := hmac.New(h.New, k.secret)
+	mac.Write(signingInput)
+
+	return mac.Sum(nil), nil
+}
+
+func (k *HMACKey) Verify(signingInput, signature []byte) error {
+	expected, err := k.Sign(signingInput)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal(signature, expected) {
+		return errInvalidSignature()
+	}
+
+	return nil
+}
+
+func (k *HMACKey) Public() VerifyingKey {
+	return k
+}
@@ -0,0 +1,319 @@
+package jwt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// KeyAlg is a JWE key management algorithm.
+type KeyAlg string
+
+const (
+	// Direct uses the shared key as the content encryption key.
+	Direct KeyAlg = "dir"
+	// RSAOAEP256 encrypts a random content encryption key with RSAES-OAEP
+	// using SHA-256.
+	RSAOAEP256 KeyAlg = "RSA-OAEP-256"
+)
+
+// ContentEnc is a JWE content encryption algorithm.
+type ContentEnc string
+
+const (
+	A256GCM ContentEnc = "A256GCM"
+)
+
+const (
+	// ContentTypeJWT marks a JWE that holds a signed JWT.
+	ContentTypeJWT = "JWT"
+
+	a256gcmKeySize = 32
+)
+
+var (
+	ErrUnsupportedEnc = errors.New("unsupported encryption algorithm")
+	ErrDecrypt        = errors.New("failed to decrypt token")
+)
+
+// JWEHeader is the protected header of a JWE.
+type JWEHeader struct {
+	Alg KeyAlg     `json:"alg"`
+	Enc ContentEnc `json:"enc"`
+	Cty string     `json:"cty,omitempty"`
+	Kid string     `json:"kid,omitempty"`
+}
+
+// EncryptionKey produces the content encryption key of a JWE.
+type EncryptionKey interface {
+	KeyAlg() KeyAlg
+	// wrapKey returns the content encryption key and its encrypted form
+	// to be put into the token.
+	wrapKey() (cek, encryptedKey []byte, err error)
+}
+
+// DecryptionKey recovers the content encryption key of a JWE.
+type DecryptionKey interface {
+	KeyAlg() KeyAlg
+	unwrapKey(encryptedKey []byte) (cek []byte, err error)
+}
+
+var (
+	_ EncryptionKey = (*DirectKey)(nil)
+	_ DecryptionKey = (*DirectKey)(nil)
+)
+
+// DirectKey is a shared 256-bit key used directly for A256GCM. It both
+// encrypts and decrypts tokens.
+type DirectKey struct {
+	key []byte
+}
+
+func NewDirectKey(key []byte) (*DirectKey, error) {
+	if len(key) != a256gcmKeySize {
+		return nil, &Err{reason: "direct key must be 32 bytes long", err: ErrInvalidKey}
+	}
+
+	return &DirectKey{key: key}, nil
+}
+
+func (k *DirectKey) KeyAlg() KeyAlg {
+	return Direct
+}
+
+func (k *DirectKey) wrapKey() ([]byte, []byte, error) {
+	return k.key, nil, nil
+}
+
+func (k *DirectKey) unwrapKey(encryptedKey []byte) ([]byte, error) {
+	if len(encryptedKey) != 0 {
+		return nil, &Err{reason: "direct encryption with an encrypted key", err: ErrDecrypt}
+	}
+
+	return k.key, nil
+}
+
+var (
+	_ EncryptionKey = (*RSAOAEPEncryptionKey)(nil)
+	_ DecryptionKey = (*RSAOAEPDecryptionKey)(nil)
+)
+
+// RSAOAEPEncryptionKey encrypts tokens with RSA-OAEP-256.
+type RSAOAEPEncryptionKey struct {
+	key *rsa.PublicKey
+}
+
+func NewRSAOAEPEncryptionKey(key *rsa.PublicKey) (*RSAOAEPEncryptionKey, error) {
+	if key == nil || key.N == nil {
+		return nil, &Err{reason: "nil RSA key", err: ErrInvalidKey}
+	}
+
+	if key.N.BitLen() < minRSAKeyBits {
+		return nil, &Err{reason: "RSA key is too short", err: ErrInvalidKey}
+	}
+
+	return &RSAOAEPEncryptionKey{key: key}, nil
+}
+
+func (k *RSAOAEPEncryptionKey) KeyAlg() KeyAlg {
+	return RSAOAEP256
+}
+
+func (k *RSAOAEPEncryptionKey) wrapKey() ([]byte, []byte, error) {
+	cek := make([]byte, a256gcmKeySize)
+	if _, err := rand.Read(cek); err != nil {
+		return nil, nil, err
+	}
+
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, k.key, cek, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cek, encryptedKey, nil
+}
+
+// RSAOAEPDecryptionKey decrypts tokens encrypted with RSA-OAEP-256.
+type RSAOAEPDecryptionKey struct {
+	key *rsa.PrivateKey
+}
+
+func NewRSAOAEPDecryptionKey(key *rsa.PrivateKey) (*RSAOAEPDecryptionKey, error) {
+	if key == nil || key.N == nil {
+		return nil, &Err{reason: "nil RSA key", err: ErrInvalidKey}
+	}
+
+	if key.N.BitLen() < minRSAKeyBits {
+		return nil, &Err{reason: "RSA key is too short", err: ErrInvalidKey}
+	}
+
+	return &RSAOAEPDecryptionKey{key: key}, nil
+}
+
+func (k *RSAOAEPDecryptionKey) KeyAlg() KeyAlg {
+	return RSAOAEP256
+}
+
+// Public returns the key that encrypts tokens for this key.
+func (k *RSAOAEPDecryptionKey) Public() *RSAOAEPEncryptionKey {
+	return &RSAOAEPEncryptionKey{key: &k.key.PublicKey}
+}
+
+func (k *RSAOAEPDecryptionKey) unwrapKey(encryptedKey []byte) ([]byte, error) {
+	cek, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, k.key, encryptedKey, nil)
+	if err != nil || len(cek) != a256gcmKeySize {
+		// The same error for every failure, so the key can't be probed.
+		return nil, &Err{reason: "invalid encrypted key", err: ErrDecrypt}
+	}
+
+	return cek, nil
+}
+
+// Encrypt encrypts the plaintext into a JWE in compact serialization with
+// A256GCM content encryption. contentType is put into the cty header.
+func Encrypt(plaintext []byte, key EncryptionKey, contentType string) (string, error) {
+	header := JWEHeader{
+		Alg: key.KeyAlg(),
+		Enc: A256GCM,
+		Cty: contentType,
+	}
+
+	headerBase64, err := encodeBase64(header)
+	if err != nil {
+		return "", err
+	}
+
+	cek, encryptedKey, err := key.wrapKey()
+	if err != nil {
+		return "", &Err{reason: "failed to encrypt the content key", err: err}
+	}
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", &Err{reason: "failed to generate iv", err: err}
+	}
+
+	// The protected header is authenticated as additional data.
+	sealed := gcm.Seal(nil, iv, plaintext, headerBase64)
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	enc := base64.RawURLEncoding
+	return strings.Join([]string{
+		string(headerBase64),
+		enc.EncodeToString(encryptedKey),
+		enc.EncodeToString(iv),
+		enc.EncodeToString(ciphertext),
+		enc.EncodeToString(tag),
+	}, "."), nil
+}
+
+// Decrypt decrypts a JWE in compact serialization and returns its header and
+// plaintext.
+func Decrypt(token string, key DecryptionKey) (JWEHeader, []byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 {
+		return JWEHeader{}, nil, &Err{reason: "invalid JWE format", err: ErrParseToken}
+	}
+
+	decodedHeader, err := decodeBase64([]byte(parts[0]))
+	if err != nil {
+		return JWEHeader{}, nil, &Err{reason: err.Error(), err: ErrInvalidHeader}
+	}
+
+	var header JWEHeader
+	if err := json.Unmarshal(decodedHeader, &header); err != nil {
+		return JWEHeader{}, nil, &Err{reason: err.Error(), err: ErrInvalidHeader}
+	}
+
+	if header.Alg != key.KeyAlg() {
+		return JWEHeader{}, nil, &Err{
+			reason: "token algorithm " + string(header.Alg) + ", key algorithm " + string(key.KeyAlg()),
+			err:    ErrAlgMismatch,
+		}
+	}
+
+	if header.Enc != A256GCM {
+		return JWEHeader{}, nil, &Err{reason: "encryption " + string(header.Enc), err: ErrUnsupportedEnc}
+	}
+
+	var decoded [4][]byte
+	for i, part := range parts[1:] {
+		decoded[i], err = decodeBase64([]byte(part))
+		if err != nil {
+			return JWEHeader{}, nil, &Err{reason: err.Error(), err: ErrParseToken}
+		}
+	}
+	encryptedKey, iv, ciphertext, tag := decoded[0], decoded[1], decoded[2], decoded[3]
+
+	cek, err := key.unwrapKey(encryptedKey)
+	if err != nil {
+		return JWEHeader{}, nil, err
+	}
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return JWEHeader{}, nil, err
+	}
+
+	if len(iv) != gcm.NonceSize() || len(tag) != gcm.Overhead() {
+		return JWEHeader{}, nil, &Err{reason: "invalid iv or tag size", err: ErrDecrypt}
+	}
+
+	plaintext, err := gcm.Open(nil, iv, append(ciphertext, tag...), []byte(parts[0]))
+	if err != nil {
+		return JWEHeader{}, nil, &Err{reason: "authentication failed", err: ErrDecrypt}
+	}
+
+	return header, plaintext, nil
+}
+
+// EncryptToken wraps a signed token into a JWE, producing a nested JWT.
+func EncryptToken(signedToken string, key EncryptionKey) (string, error) {
+	return Encrypt([]byte(signedToken), key, ContentTypeJWT)
+}
+
+// DecryptToken unwraps a nested JWT created by EncryptToken and returns the
+// signed token, which still has to be parsed.
+func DecryptToken(token string, key DecryptionKey) (string, error) {
+	header, plaintext, err := Decrypt(token, key)
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.EqualFold(header.Cty, ContentTypeJWT) {
+		return "", &Err{reason: "content type " + header.Cty + " is not a JWT", err: ErrParseToken}
+	}
+
+	return string(plaintext), nil
+}
+
+// IsEncrypted reports whether the token looks like a JWE in compact
+// serialization rather than a signed JWT.
+func IsEncrypted(token string) bool {
+	return strings.Count(token, ".") == 4
+}
+
+func newGCM(cek []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, &Err{reason: "invalid content key", err: ErrDecrypt}
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, &Err{reason: "invalid content key", err: ErrDecrypt}
+	}
+
+	return gcm, nil
+}
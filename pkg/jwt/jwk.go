@@ -0,0 +1,281 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+)
+
+var (
+	ErrUnsupportedJWK = errors.New("unsupported JSON Web Key")
+)
+
+// Key types and curves of RFC 7518 and RFC 8037.
+const (
+	KeyTypeRSA = "RSA"
+	KeyTypeEC  = "EC"
+	KeyTypeOKP = "OKP"
+	KeyTypeOct = "oct"
+
+	CurveP256    = "P-256"
+	CurveP384    = "P-384"
+	CurveP521    = "P-521"
+	CurveEd25519 = "Ed25519"
+
+	KeyUseSignature = "sig"
+)
+
+// JWK is a JSON Web Key (RFC 7517). NewJWK describes public keys only,
+// NewPrivateJWK fills in the private parameters as well.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg Alg    `json:"alg,omitempty"`
+
+	// RSA public key.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC and OKP public key. Y is empty for OKP.
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+
+	// Private parameters. D is the private exponent of an RSA key or the
+	// private key of an EC or OKP key; K is an HMAC secret.
+	D  string `json:"d,omitempty"`
+	P  string `json:"p,omitempty"`
+	Q  string `json:"q,omitempty"`
+	DP string `json:"dp,omitempty"`
+	DQ string `json:"dq,omitempty"`
+	QI string `json:"qi,omitempty"`
+	K  string `json:"k,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Key returns the key with the ID, so a JWKS can be used as a KeySet.
+func (s JWKS) Key(kid string) (VerifyingKey, error) {
+	for _, jwk := range s.Keys {
+		if jwk.Kid == kid {
+			return jwk.VerifyingKey()
+		}
+	}
+
+	return nil, &Err{reason: "key " + kid, err: ErrKeyNotFound}
+}
+
+// NewJWK describes the verifying key as a JWK. HMAC keys can't be published
+// and return ErrUnsupportedJWK.
+func NewJWK(kid string, key VerifyingKey) (JWK, error) {
+	jwk := JWK{
+		Kid: kid,
+		Use: KeyUseSignature,
+		Alg: key.Alg(),
+	}
+
+	switch k := key.(type) {
+	case *RSAVerifyingKey:
+		jwk.Kty = KeyTypeRSA
+		jwk.N = encodeBigInt(k.key.N)
+		jwk.E = encodeBigInt(big.NewInt(int64(k.key.E)))
+	case *ECDSAVerifyingKey:
+		crv, err := curveName(k.key.Curve)
+		if err != nil {
+			return JWK{}, err
+		}
+
+		ecdh, err := k.key.ECDH()
+		if err != nil {
+			return JWK{}, &Err{reason: "invalid ECDSA key", err: err}
+		}
+
+		// Uncompressed point: 0x04 || X || Y.
+		point := ecdh.Bytes()[1:]
+		size := len(point) / 2
+
+		jwk.Kty = KeyTypeEC
+		jwk.Crv = crv
+		jwk.X = base64.RawURLEncoding.EncodeToString(point[:size])
+		jwk.Y = base64.RawURLEncoding.EncodeToString(point[size:])
+	case *Ed25519VerifyingKey:
+		jwk.Kty = KeyTypeOKP
+		jwk.Crv = CurveEd25519
+		jwk.X = base64.RawURLEncoding.EncodeToString(k.key)
+	default:
+		return JWK{}, &Err{reason: "key can't be published", err: ErrUnsupportedJWK}
+	}
+
+	return jwk, nil
+}
+
+// NewPrivateJWK describes the signing key with its private parameters, e.g.
+// to store it. The result must never be published.
+func NewPrivateJWK(kid string, key SigningKey) (JWK, error) {
+	if k, ok := key.(identifiedKey); ok {
+		key = k.SigningKey
+	}
+
+	if k, ok := key.(*HMACKey); ok {
+		return JWK{
+			Kty: KeyTypeOct,
+			Kid: kid,
+			Use: KeyUseSignature,
+			Alg: k.alg,
+			K:   base64.RawURLEncoding.EncodeToString(k.secret),
+		}, nil
+	}
+
+	jwk, err := NewJWK(kid, key.Public())
+	if err != nil {
+		return JWK{}, err
+	}
+
+	switch k := key.(type) {
+	case *RSASigningKey:
+		k.key.Precompute()
+		jwk.D = encodeBigInt(k.key.D)
+		jwk.P = encodeBigInt(k.key.Primes[0])
+		jwk.Q = encodeBigInt(k.key.Primes[1])
+		jwk.DP = encodeBigInt(k.key.Precomputed.Dp)
+		jwk.DQ = encodeBigInt(k.key.Precomputed.Dq)
+		jwk.QI = encodeBigInt(k.key.Precomputed.Qinv)
+	case *ECDSASigningKey:
+		ecdh, err := k.key.ECDH()
+		if err != nil {
+			return JWK{}, &Err{reason: "invalid ECDSA key", err: err}
+		}
+
+		jwk.D = base64.RawURLEncoding.EncodeToString(ecdh.Bytes())
+	case *Ed25519SigningKey:
+		jwk.D = base64.RawURLEncoding.EncodeToString(k.key.Seed())
+	default:
+		return JWK{}, &Err{reason: "unsupported signing key", err: ErrUnsupportedJWK}
+	}
+
+	return jwk, nil
+}
+
+// VerifyingKey creates the verifying key described by the JWK. The JWK must
+// specify its algorithm.
+func (j JWK) VerifyingKey() (VerifyingKey, error) {
+	if j.Use != "" && j.Use != KeyUseSignature {
+		return nil, &Err{reason: "key use " + j.Use, err: ErrUnsupportedJWK}
+	}
+
+	if j.Alg == "" {
+		return nil, &Err{reason: "alg missing", err: ErrUnsupportedJWK}
+	}
+
+	switch j.Kty {
+	case KeyTypeRSA:
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, err
+		}
+
+		if !e.IsInt64() || e.Int64() > int64(^uint32(0)>>1) {
+			return nil, &Err{reason: "RSA exponent is too large", err: ErrUnsupportedJWK}
+		}
+
+		return NewRSAVerifyingKey(j.Alg, &rsa.PublicKey{N: n, E: int(e.Int64())})
+	case KeyTypeEC:
+		curve, err := curveByName(j.Crv)
+		if err != nil {
+			return nil, err
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, &Err{reason: "invalid x", err: ErrUnsupportedJWK}
+		}
+
+		y, err := base64.RawURLEncoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, &Err{reason: "invalid y", err: ErrUnsupportedJWK}
+		}
+
+		size := curveByteSize(curve)
+		if len(x) != size || len(y) != size {
+			return nil, &Err{reason: "invalid point size", err: ErrUnsupportedJWK}
+		}
+
+		key := &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+
+		// ECDH fails for points that are not on the curve.
+		if _, err := key.ECDH(); err != nil {
+			return nil, &Err{reason: "invalid EC point", err: ErrUnsupportedJWK}
+		}
+
+		return NewECDSAVerifyingKey(j.Alg, key)
+	case KeyTypeOKP:
+		if j.Crv != CurveEd25519 {
+			return nil, &Err{reason: "curve " + j.Crv, err: ErrUnsupportedJWK}
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, &Err{reason: "invalid x", err: ErrUnsupportedJWK}
+		}
+
+		return NewEd25519VerifyingKey(j.Alg, ed25519.PublicKey(x))
+	default:
+		return nil, &Err{reason: "key type " + j.Kty, err: ErrUnsupportedJWK}
+	}
+}
+
+func encodeBigInt(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, &Err{reason: "invalid integer", err: ErrUnsupportedJWK}
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
+
+func curveName(curve elliptic.Curve) (string, error) {
+	switch curve {
+	case elliptic.P256():
+		return CurveP256, nil
+	case elliptic.P384():
+		return CurveP384, nil
+	case elliptic.P521():
+		return CurveP521, nil
+	default:
+		return "", &Err{reason: "curve " + curve.Params().Name, err: ErrUnsupportedJWK}
+	}
+}
+
+func curveByName(name string) (elliptic.Curve, error) {
+	switch name {
+	case CurveP256:
+		return elliptic.P256(), nil
+	case CurveP384:
+		return elliptic.P384(), nil
+	case CurveP521:
+		return elliptic.P521(), nil
+	default:
+		return nil, &Err{reason: "curve " + name, err: ErrUnsupportedJWK}
+	}
+}
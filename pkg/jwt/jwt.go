@@ -1,20 +1,31 @@
 package jwt
 
 import (
-	"crypto/hmac"
-	"crypto/sha512"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/passwordhash/jwt-test-task/pkg/clock"
 )
 
 var (
 	ErrInvalidToken = errors.New("invalid token")
 	ErrParseToken   = errors.New("failed to parse token")
 	ErrTokenExpired = errors.New("token expired")
+
+	ErrInvalidHeader  = errors.New("invalid token header")
+	ErrUnsupportedAlg = errors.New("unsupported signing algorithm")
+	ErrAlgMismatch    = errors.New("signing algorithm doesn't match the key")
+	ErrInvalidKey     = errors.New("invalid key")
+
+	ErrTokenNotValidYet = errors.New("token is not valid yet")
+	ErrTokenTooOld      = errors.New("token is too old")
+	ErrInvalidIssuer    = errors.New("invalid token issuer")
+	ErrInvalidAudience  = errors.New("invalid token audience")
 )
 
 type Err struct {
@@ -26,12 +37,37 @@ func (e *Err) Error() string {
 	return fmt.Sprintf("jwt error: %s, details: %v", e.reason, e.err)
 }
 
+func (e *Err) Unwrap() error {
+	return e.err
+}
+
 type Alg string
 
 const (
+	HS256 Alg = "HS256"
+	HS384 Alg = "HS384"
 	HS512 Alg = "HS512"
+
+	RS256 Alg = "RS256"
+	RS384 Alg = "RS384"
+	RS512 Alg = "RS512"
+
+	ES256 Alg = "ES256"
+	ES384 Alg = "ES384"
+	ES512 Alg = "ES512"
+
+	EdDSA Alg = "EdDSA"
 )
 
+// supportedAlgs lists the algorithms a token may be signed with. Notably,
+// "none" is not one of them.
+var supportedAlgs = map[Alg]struct{}{
+	HS256: {}, HS384: {}, HS512: {},
+	RS256: {}, RS384: {}, RS512: {},
+	ES256: {}, ES384: {}, ES512: {},
+	EdDSA: {},
+}
+
 const (
 	JWTType = "jwt"
 )
@@ -39,22 +75,68 @@ const (
 type Header struct {
 	Alg Alg    `json:"alg"`
 	Typ string `json:"typ"`
+	Kid string `json:"kid,omitempty"`
 }
 
 type Payload map[string]any
 
-func NewToken(alg string, claims map[string]any, ttl time.Duration, secret string) (string, error) {
-	var err error
-	now := time.Now()
+// Registered extracts the registered claims, so a Payload can be used with
+// NewTokenFrom. Claims of an invalid type are left empty.
+func (p Payload) Registered() RegisteredClaims {
+	var claims RegisteredClaims
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		return claims
+	}
+
+	_ = json.Unmarshal(b, &claims)
+
+	return claims
+}
+
+// TokenOption configures NewToken.
+type TokenOption func(*tokenOptions)
+
+type tokenOptions struct {
+	clock clock.Clock
+}
+
+// WithTokenClock sets the clock the iat and exp claims are computed from.
+// It defaults to the system clock.
+func WithTokenClock(c clock.Clock) TokenOption {
+	return func(o *tokenOptions) {
+		o.clock = c
+	}
+}
+
+// NewToken creates a token with the claims signed by the key. The iat and
+// exp claims are set from the current time and ttl. If the key implements
+// Identified, its ID is put into the kid header.
+func NewToken(claims map[string]any, ttl time.Duration, key SigningKey, opts ...TokenOption) (string, error) {
+	o := tokenOptions{clock: clock.System{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	now := o.clock.Now()
 
 	claims["iat"] = now.Unix()
 	claims["exp"] = now.Add(ttl).Unix()
 
+	return newToken(claims, key)
+}
+
+func newToken(claims any, key SigningKey) (string, error) {
 	header := Header{
-		Alg: Alg(alg), // TODO: validate alg
+		Alg: key.Alg(),
 		Typ: JWTType,
 	}
 
+	if k, ok := key.(Identified); ok {
+		header.Kid = k.KID()
+	}
+
 	headerBase64, err := encodeBase64(header)
 	if err != nil {
 		return "", &Err{reason: "encoding header in base 64 failed", err: err}
@@ -65,7 +147,7 @@ func NewToken(alg string, claims map[string]any, ttl time.Duration, secret strin
 		return "", &Err{reason: "encoding payload in base 64 failed", err: err}
 	}
 
-	signature, err := sign(header.Alg, headerBase64, payloadBase64, []byte(secret)) // TODO: returns string or byte slice ?
+	signature, err := key.Sign(signingInput(headerBase64, payloadBase64))
 	if err != nil {
 		return "", &Err{reason: "signing failed", err: err}
 	}
@@ -77,19 +159,13 @@ func NewToken(alg string, claims map[string]any, ttl time.Duration, secret strin
 	return token, nil
 }
 
-func sign(alg Alg, encodedHeader, encodedPayload, secret []byte) ([]byte, error) {
-	switch alg {
-	case HS512:
-		mac := hmac.New(sha512.New, secret)
+func signingInput(encodedHeader, encodedPayload []byte) []byte {
+	input := make([]byte, 0, len(encodedHeader)+1+len(encodedPayload))
+	input = append(input, encodedHeader...)
+	input = append(input, '.')
+	input = append(input, encodedPayload...)
 
-		mac.Write(encodedHeader)
-		mac.Write([]byte{'.'})
-		mac.Write(encodedPayload)
-
-		return mac.Sum(nil), nil
-	default:
-		return nil, &Err{reason: "unsupported algorithm", err: nil}
-	}
+	return input
 }
 
 func encodeBase64(data any) ([]byte, error) {
@@ -104,57 +180,44 @@ func encodeBase64(data any) ([]byte, error) {
 	return buf, nil
 }
 
-func ParseToken(token, secret string) (Payload, error) {
-	parts := strings.Split(token, ".")
-	if len(parts) < 3 {
-		return nil, &Err{reason: "invalid token format", err: ErrParseToken}
-	}
-
-	header := []byte(parts[0])
-	payload := []byte(parts[1])
-	signature := []byte(parts[2])
-
-	decodedSignature, err := decodeBase64(signature)
+func decodeHeader(encodedHeader []byte) (Header, error) {
+	decoded, err := decodeBase64(encodedHeader)
 	if err != nil {
-		return nil, &Err{reason: err.Error(), err: ErrParseToken}
+		return Header{}, &Err{reason: err.Error(), err: ErrInvalidHeader}
 	}
 
-	// TODO: alg from header
-	computedSignature, err := sign(HS512, header, payload, []byte(secret))
-	if err != nil {
-		return nil, &Err{reason: err.Error(), err: ErrParseToken}
+	var header Header
+	if err := json.Unmarshal(decoded, &header); err != nil {
+		return Header{}, &Err{reason: err.Error(), err: ErrInvalidHeader}
 	}
 
-	if !hmac.Equal(decodedSignature, computedSignature) {
-		// TODO: change
-		return nil, &Err{reason: "invalid signature", err: ErrParseToken}
+	if header.Alg == "" {
+		return Header{}, &Err{reason: "alg header missing", err: ErrInvalidHeader}
 	}
 
-	decodedPayload, err := decodeBase64(payload)
-	if err != nil {
-		return nil, &Err{reason: err.Error(), err: ErrParseToken}
-	}
-
-	var p map[string]any
-	if err := json.Unmarshal(decodedPayload, &p); err != nil {
-		return nil, &Err{reason: err.Error(), err: ErrParseToken}
-	}
+	return header, nil
+}
 
-	expRaw, exists := p["exp"]
-	if !exists {
-		return nil, &Err{reason: "exp claim missing", err: ErrParseToken}
+// validateAlg checks that the header algorithm can be used with the key.
+// The algorithm is never taken from the header alone, so a token can't
+// switch the verification to another algorithm.
+func validateAlg(header Header, key VerifyingKey, allowedAlgs []Alg) error {
+	if _, ok := supportedAlgs[header.Alg]; !ok {
+		return &Err{reason: "algorithm " + string(header.Alg), err: ErrUnsupportedAlg}
 	}
 
-	expFloat, ok := expRaw.(float64)
-	if !ok {
-		return nil, &Err{reason: "exp claim has invalid type", err: ErrParseToken}
+	if len(allowedAlgs) > 0 && !slices.Contains(allowedAlgs, header.Alg) {
+		return &Err{reason: "algorithm " + string(header.Alg) + " is not allowed", err: ErrUnsupportedAlg}
 	}
 
-	if time.Now().Unix() > int64(expFloat) {
-		return nil, &Err{reason: ErrTokenExpired.Error(), err: ErrTokenExpired}
+	if header.Alg != key.Alg() {
+		return &Err{
+			reason: fmt.Sprintf("token algorithm %s, key algorithm %s", header.Alg, key.Alg()),
+			err:    ErrAlgMismatch,
+		}
 	}
 
-	return p, nil
+	return nil
 }
 
 func decodeBase64(data []byte) ([]byte, error) {
@@ -163,3 +226,30 @@ func decodeBase64(data []byte) ([]byte, error) {
 
 	return buf, err
 }
+
+// Decode returns the header and claims of a signed token WITHOUT verifying
+// its signature or claims. It is meant for debugging and logging only; never
+// trust the result.
+func Decode(token string) (Header, Payload, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Header{}, nil, &Err{reason: "invalid token format", err: ErrParseToken}
+	}
+
+	header, err := decodeHeader([]byte(parts[0]))
+	if err != nil {
+		return Header{}, nil, err
+	}
+
+	decodedPayload, err := decodeBase64([]byte(parts[1]))
+	if err != nil {
+		return Header{}, nil, &Err{reason: err.Error(), err: ErrParseToken}
+	}
+
+	var claims Payload
+	if err := json.Unmarshal(decodedPayload, &claims); err != nil {
+		return Header{}, nil, &Err{reason: err.Error(), err: ErrParseToken}
+	}
+
+	return header, claims, nil
+}
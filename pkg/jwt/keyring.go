@@ -0,0 +1,304 @@
+package jwt
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrKeyNotFound = errors.New("key not found")
+)
+
+// KeySet resolves the key that verifies a token by the kid header.
+type KeySet interface {
+	// Key returns the verifying key with the ID. kid is empty for tokens
+	// without the kid header.
+	Key(kid string) (VerifyingKey, error)
+}
+
+// Identified is implemented by signing keys that carry a key ID. NewToken
+// puts the ID into the kid header.
+type Identified interface {
+	KID() string
+}
+
+type identifiedKey struct {
+	SigningKey
+	kid string
+}
+
+func (k identifiedKey) KID() string {
+	return k.kid
+}
+
+// WithKID attaches the key ID to the signing key.
+func WithKID(kid string, key SigningKey) SigningKey {
+	return identifiedKey{SigningKey: key, kid: kid}
+}
+
+type staticKeySet struct {
+	key VerifyingKey
+}
+
+func (s staticKeySet) Key(string) (VerifyingKey, error) {
+	return s.key, nil
+}
+
+// Keyring holds the keys of a key rotation. One key is active and signs new
+// tokens, every key that is not retired verifies them. This way a new key
+// can be introduced and the old one retired once the tokens it signed have
+// expired, without invalidating every issued token at once.
+//
+// Keyring is safe for concurrent use.
+type Keyring struct {
+	mu        sync.RWMutex
+	keys      map[string]*keyringEntry
+	activeKID string
+}
+
+type keyringEntry struct {
+	signing   SigningKey
+	verifying VerifyingKey
+	retired   bool
+}
+
+func NewKeyring() *Keyring {
+	return &Keyring{
+		keys: make(map[string]*keyringEntry),
+	}
+}
+
+// AddSigningKey adds a key that may become active.
+func (k *Keyring) AddSigningKey(kid string, key SigningKey) error {
+	return k.add(kid, &keyringEntry{signing: key, verifying: key.Public()})
+}
+
+// AddVerifyingKey adds a key that only verifies tokens, e.g. the public key
+// of a key whose private part lives elsewhere.
+func (k *Keyring) AddVerifyingKey(kid string, key VerifyingKey) error {
+	return k.add(kid, &keyringEntry{verifying: key})
+}
+
+func (k *Keyring) add(kid string, entry *keyringEntry) error {
+	if kid == "" {
+		return &Err{reason: "empty key ID", err: nil}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, ok := k.keys[kid]; ok {
+		return &Err{reason: "duplicate key ID " + kid, err: nil}
+	}
+
+	k.keys[kid] = entry
+
+	return nil
+}
+
+// SetActive makes the key sign new tokens. The key must be a signing key
+// and must not be retired.
+func (k *Keyring) SetActive(kid string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	entry, ok := k.keys[kid]
+	if !ok {
+		return &Err{reason: "key " + kid, err: ErrKeyNotFound}
+	}
+
+	if entry.signing == nil {
+		return &Err{reason: "key " + kid + " can't sign tokens", err: nil}
+	}
+
+	if entry.retired {
+		return &Err{reason: "key " + kid + " is retired", err: nil}
+	}
+
+	k.activeKID = kid
+
+	return nil
+}
+
+// Retire stops accepting tokens signed by the key. The active key can't be
+// retired.
+func (k *Keyring) Retire(kid string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	entry, ok := k.keys[kid]
+	if !ok {
+		return &Err{reason: "key " + kid, err: ErrKeyNotFound}
+	}
+
+	if kid == k.activeKID {
+		return &Err{reason: "active key " + kid + " can't be retired", err: nil}
+	}
+
+	entry.retired = true
+
+	return nil
+}
+
+// SigningKey returns the active key. The key carries its ID, see Identified.
+func (k *Keyring) SigningKey() (SigningKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	entry, ok := k.keys[k.activeKID]
+	if !ok {
+		return nil, &Err{reason: "no active key", err: ErrKeyNotFound}
+	}
+
+	return WithKID(k.activeKID, entry.signing), nil
+}
+
+// Key returns the verifying key with the ID. Tokens without the kid header
+// are verified with the active key.
+func (k *Keyring) Key(kid string) (VerifyingKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if kid == "" {
+		kid = k.activeKID
+	}
+
+	entry, ok := k.keys[kid]
+	if !ok || entry.retired {
+		return nil, &Err{reason: "key " + kid, err: ErrKeyNotFound}
+	}
+
+	return entry.verifying, nil
+}
+
+// KIDs returns the IDs of the keys that are not retired, sorted.
+func (k *Keyring) KIDs() []string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	kids := make([]string, 0, len(k.keys))
+	for kid, entry := range k.keys {
+		if !entry.retired {
+			kids = append(kids, kid)
+		}
+	}
+	sort.Strings(kids)
+
+	return kids
+}
+
+// LoadDir adds every key found in the directory. The file name without the
+// extension is the key ID:
+//
+//   - <kid>.pem holds a private key, which becomes a signing key, or a
+//     public key, which becomes a verifying key;
+//   - <kid>.secret holds an HMAC secret.
+//
+// The algorithm is derived from the key, see AlgForKey. Other files are
+// ignored.
+func (k *Keyring) LoadDir(dir string, preferred Alg) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return &Err{reason: "failed to read key directory", err: err}
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		ext := filepath.Ext(e.Name())
+		kid := strings.TrimSuffix(e.Name(), ext)
+
+		if ext != ".pem" && ext != ".secret" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return &Err{reason: "failed to read key " + e.Name(), err: err}
+		}
+
+		if ext == ".secret" {
+			secret := []byte(strings.TrimSpace(string(data)))
+			if err := k.addKey(kid, preferred, secret, true); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if key, err := ParsePrivateKeyPEM(data); err == nil {
+			if err := k.addKey(kid, preferred, key, true); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		key, err := ParsePublicKeyPEM(data)
+		if err != nil {
+			return &Err{reason: "failed to parse key " + e.Name(), err: err}
+		}
+
+		if err := k.addKey(kid, preferred, key, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (k *Keyring) addKey(kid string, preferred Alg, key any, signing bool) error {
+	alg, err := AlgForKey(key, preferred)
+	if err != nil {
+		return err
+	}
+
+	if signing {
+		sk, err := NewSigningKey(alg, key)
+		if err != nil {
+			return err
+		}
+
+		return k.AddSigningKey(kid, sk)
+	}
+
+	vk, err := NewVerifyingKey(alg, key)
+	if err != nil {
+		return err
+	}
+
+	return k.AddVerifyingKey(kid, vk)
+}
+
+// JWKS returns the keys that are not retired as a JSON Web Key Set. HMAC keys
+// are secret and are never included.
+func (k *Keyring) JWKS() JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := JWKS{Keys: make([]JWK, 0, len(k.keys))}
+	for kid, entry := range k.keys {
+		if entry.retired {
+			continue
+		}
+
+		jwk, err := NewJWK(kid, entry.verifying)
+		if err != nil {
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+
+	return set
+}
@@ -0,0 +1,134 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+)
+
+// SigningKey signs tokens with a single algorithm.
+type SigningKey interface {
+	// Alg returns the algorithm the key signs with.
+	Alg() Alg
+	// Sign returns the signature of the signing input, i.e. the
+	// "<header>.<payload>" part of the token.
+	Sign(signingInput []byte) ([]byte, error)
+	// Public returns the key that verifies signatures made by this key.
+	// For HMAC keys it is the key itself.
+	Public() VerifyingKey
+}
+
+// VerifyingKey verifies token signatures made with a single algorithm.
+type VerifyingKey interface {
+	// Alg returns the algorithm the key verifies.
+	Alg() Alg
+	// Verify returns an error if the signature doesn't match the signing input.
+	Verify(signingInput, signature []byte) error
+}
+
+// NewSigningKey creates a signing key for the algorithm. key is a []byte
+// secret for HMAC algorithms, or *rsa.PrivateKey, *ecdsa.PrivateKey and
+// ed25519.PrivateKey for the asymmetric ones.
+func NewSigningKey(alg Alg, key any) (SigningKey, error) {
+	switch k := key.(type) {
+	case []byte:
+		return NewHMACKey(alg, k)
+	case *rsa.PrivateKey:
+		return NewRSASigningKey(alg, k)
+	case *ecdsa.PrivateKey:
+		return NewECDSASigningKey(alg, k)
+	case ed25519.PrivateKey:
+		return NewEd25519SigningKey(alg, k)
+	default:
+		return nil, &Err{reason: fmt.Sprintf("unsupported signing key type %T", key), err: ErrInvalidKey}
+	}
+}
+
+// NewVerifyingKey creates a verifying key for the algorithm. key is a []byte
+// secret for HMAC algorithms, or *rsa.PublicKey, *ecdsa.PublicKey and
+// ed25519.PublicKey for the asymmetric ones.
+func NewVerifyingKey(alg Alg, key any) (VerifyingKey, error) {
+	switch k := key.(type) {
+	case []byte:
+		return NewHMACKey(alg, k)
+	case *rsa.PublicKey:
+		return NewRSAVerifyingKey(alg, k)
+	case *ecdsa.PublicKey:
+		return NewECDSAVerifyingKey(alg, k)
+	case ed25519.PublicKey:
+		return NewEd25519VerifyingKey(alg, k)
+	default:
+		return nil, &Err{reason: fmt.Sprintf("unsupported verifying key type %T", key), err: ErrInvalidKey}
+	}
+}
+
+func hashFor(alg Alg) (crypto.Hash, bool) {
+	switch alg {
+	case HS256, RS256, ES256:
+		return crypto.SHA256, true
+	case HS384, RS384, ES384:
+		return crypto.SHA384, true
+	case HS512, RS512, ES512:
+		return crypto.SHA512, true
+	default:
+		return 0, false
+	}
+}
+
+func digest(h crypto.Hash, data []byte) []byte {
+	hasher := h.New()
+	hasher.Write(data)
+
+	return hasher.Sum(nil)
+}
+
+func errInvalidSignature() error {
+	return &Err{reason: "invalid signature", err: nil}
+}
+
+// AlgForKey returns the algorithm to use with the key material. preferred is
+// returned if it fits the key type. Otherwise it is HS512 for HMAC secrets,
+// RS512 for RSA keys, the curve's algorithm for ECDSA keys and EdDSA for
+// Ed25519 keys.
+func AlgForKey(key any, preferred Alg) (Alg, error) {
+	switch k := key.(type) {
+	case []byte:
+		return pickAlg(preferred, HS512, HS256, HS384, HS512), nil
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		return pickAlg(preferred, RS512, RS256, RS384, RS512), nil
+	case *ecdsa.PrivateKey:
+		return ecdsaAlg(&k.PublicKey)
+	case *ecdsa.PublicKey:
+		return ecdsaAlg(k)
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		return EdDSA, nil
+	default:
+		return "", &Err{reason: fmt.Sprintf("unsupported key type %T", key), err: ErrInvalidKey}
+	}
+}
+
+func pickAlg(preferred, fallback Alg, family ...Alg) Alg {
+	for _, alg := range family {
+		if alg == preferred {
+			return preferred
+		}
+	}
+
+	return fallback
+}
+
+func ecdsaAlg(key *ecdsa.PublicKey) (Alg, error) {
+	for _, alg := range []Alg{ES256, ES384, ES512} {
+		if checkECDSA(alg, key) == nil {
+			return alg, nil
+		}
+	}
+
+	if key == nil || key.Curve == nil {
+		return "", &Err{reason: "nil ECDSA key", err: ErrInvalidKey}
+	}
+
+	return "", &Err{reason: "unsupported ECDSA curve " + key.Curve.Params().Name, err: ErrInvalidKey}
+}
@@ -0,0 +1,107 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+
+	"github.com/passwordhash/jwt-test-task/pkg/jwt"
+)
+
+func TestNewKeyErrors(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		newKey  func() error
+		wantErr error
+	}{
+		{
+			name: "empty HMAC secret",
+			newKey: func() error {
+				_, err := jwt.NewHMACKey(jwt.HS256, nil)
+				return err
+			},
+			wantErr: jwt.ErrInvalidKey,
+		},
+		{
+			name: "HMAC key with RSA algorithm",
+			newKey: func() error {
+				_, err := jwt.NewHMACKey(jwt.RS256, []byte("secret"))
+				return err
+			},
+			wantErr: jwt.ErrAlgMismatch,
+		},
+		{
+			name: "nil ECDSA key",
+			newKey: func() error {
+				_, err := jwt.NewECDSAVerifyingKey(jwt.ES256, nil)
+				return err
+			},
+			wantErr: jwt.ErrInvalidKey,
+		},
+		{
+			name: "nil ECDSA curve",
+			newKey: func() error {
+				_, err := jwt.NewECDSAVerifyingKey(jwt.ES256, &ecdsa.PublicKey{})
+				return err
+			},
+			wantErr: jwt.ErrInvalidKey,
+		},
+		{
+			name: "ECDSA curve mismatch",
+			newKey: func() error {
+				_, err := jwt.NewECDSASigningKey(jwt.ES384, ecKey)
+				return err
+			},
+			wantErr: jwt.ErrAlgMismatch,
+		},
+		{
+			name: "nil RSA key",
+			newKey: func() error {
+				_, err := jwt.NewRSASigningKey(jwt.RS256, nil)
+				return err
+			},
+			wantErr: jwt.ErrInvalidKey,
+		},
+		{
+			name: "nil RSA modulus",
+			newKey: func() error {
+				_, err := jwt.NewRSAVerifyingKey(jwt.RS256, &rsa.PublicKey{})
+				return err
+			},
+			wantErr: jwt.ErrInvalidKey,
+		},
+		{
+			name: "Ed25519 key with short seed",
+			newKey: func() error {
+				_, err := jwt.NewEd25519SigningKey(jwt.EdDSA, make([]byte, 16))
+				return err
+			},
+			wantErr: jwt.ErrInvalidKey,
+		},
+		{
+			name: "unsupported verifying key type",
+			newKey: func() error {
+				_, err := jwt.NewVerifyingKey(jwt.HS256, "secret")
+				return err
+			},
+			wantErr: jwt.ErrInvalidKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.newKey()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package jwt
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/passwordhash/jwt-test-task/pkg/clock"
+)
+
+// ParseOption configures a Parser.
+type ParseOption func(*parseOptions)
+
+type parseOptions struct {
+	skipExpiration bool
+	allowedAlgs    []Alg
+	leeway         time.Duration
+	issuer         string
+	audience       string
+	maxAge         time.Duration
+	clock          clock.Clock
+}
+
+// WithClock sets the clock the time claims are checked against. It defaults
+// to the system clock.
+func WithClock(c clock.Clock) ParseOption {
+	return func(o *parseOptions) {
+		o.clock = c
+	}
+}
+
+// WithAllowedAlgs restricts the algorithms a token may be signed with.
+// The header algorithm must still match the key.
+func WithAllowedAlgs(algs ...Alg) ParseOption {
+	return func(o *parseOptions) {
+		o.allowedAlgs = algs
+	}
+}
+
+// WithoutExpiration disables the exp claim check. The signature is still
+// verified. It is meant for flows that accept an expired token, e.g. refresh.
+func WithoutExpiration() ParseOption {
+	return func(o *parseOptions) {
+		o.skipExpiration = true
+	}
+}
+
+// WithLeeway allows for clock skew between the issuer and the parser when
+// the exp, nbf and iat claims are checked.
+func WithLeeway(leeway time.Duration) ParseOption {
+	return func(o *parseOptions) {
+		o.leeway = leeway
+	}
+}
+
+// WithIssuer requires the iss claim to be equal to iss.
+func WithIssuer(iss string) ParseOption {
+	return func(o *parseOptions) {
+		o.issuer = iss
+	}
+}
+
+// WithAudience requires the aud claim to contain aud.
+func WithAudience(aud string) ParseOption {
+	return func(o *parseOptions) {
+		o.audience = aud
+	}
+}
+
+// WithMaxAge requires the iat claim and rejects tokens issued more than
+// maxAge ago, regardless of their exp claim.
+func WithMaxAge(maxAge time.Duration) ParseOption {
+	return func(o *parseOptions) {
+		o.maxAge = maxAge
+	}
+}
+
+// Parser verifies tokens and validates their registered claims. The exp
+// claim is always required unless WithoutExpiration is set; nbf is checked
+// when present; iss, aud and iat are checked when the matching option is set.
+type Parser struct {
+	opts parseOptions
+}
+
+func NewParser(opts ...ParseOption) *Parser {
+	p := &Parser{
+		opts: parseOptions{clock: clock.System{}},
+	}
+	for _, opt := range opts {
+		opt(&p.opts)
+	}
+
+	return p
+}
+
+// Parse verifies the token with the key picked from the set by the kid
+// header and returns its claims.
+func (p *Parser) Parse(token string, keys KeySet) (Payload, error) {
+	decodedPayload, err := p.verify(token, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims Payload
+	if err := json.Unmarshal(decodedPayload, &claims); err != nil {
+		return nil, &Err{reason: err.Error(), err: ErrParseToken}
+	}
+
+	return claims, nil
+}
+
+// ParseToken verifies the token signature with the key and returns its claims.
+// The header algorithm must be supported and match the key's algorithm,
+// otherwise ErrUnsupportedAlg or ErrAlgMismatch is returned.
+func ParseToken(token string, key VerifyingKey, opts ...ParseOption) (Payload, error) {
+	return NewParser(opts...).Parse(token, staticKeySet{key: key})
+}
+
+// ParseTokenWithKeySet is like ParseToken, but the verifying key is picked
+// from the set by the kid header.
+func ParseTokenWithKeySet(token string, keys KeySet, opts ...ParseOption) (Payload, error) {
+	return NewParser(opts...).Parse(token, keys)
+}
+
+// verify checks the token signature and registered claims and returns the
+// decoded payload.
+func (p *Parser) verify(token string, keys KeySet) ([]byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, &Err{reason: "invalid token format", err: ErrParseToken}
+	}
+
+	header := []byte(parts[0])
+	payload := []byte(parts[1])
+	signature := []byte(parts[2])
+
+	decodedHeader, err := decodeHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := keys.Key(decodedHeader.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateAlg(decodedHeader, key, p.opts.allowedAlgs); err != nil {
+		return nil, err
+	}
+
+	decodedSignature, err := decodeBase64(signature)
+	if err != nil {
+		return nil, &Err{reason: err.Error(), err: ErrParseToken}
+	}
+
+	if err := key.Verify(signingInput(header, payload), decodedSignature); err != nil {
+		return nil, &Err{reason: "invalid signature", err: ErrParseToken}
+	}
+
+	decodedPayload, err := decodeBase64(payload)
+	if err != nil {
+		return nil, &Err{reason: err.Error(), err: ErrParseToken}
+	}
+
+	var claims RegisteredClaims
+	if err := json.Unmarshal(decodedPayload, &claims); err != nil {
+		return nil, &Err{reason: err.Error(), err: ErrParseToken}
+	}
+
+	if err := p.validate(claims, p.opts.clock.Now()); err != nil {
+		return nil, err
+	}
+
+	return decodedPayload, nil
+}
+
+func (p *Parser) validate(claims RegisteredClaims, now time.Time) error {
+	leeway := p.opts.leeway
+
+	if !p.opts.skipExpiration {
+		if claims.ExpiresAt == nil {
+			return &Err{reason: "exp claim missing", err: ErrParseToken}
+		}
+
+		if now.After(claims.ExpiresAt.Time.Add(leeway)) {
+			return &Err{reason: ErrTokenExpired.Error(), err: ErrTokenExpired}
+		}
+	}
+
+	if claims.NotBefore != nil && now.Add(leeway).Before(claims.NotBefore.Time) {
+		return &Err{reason: ErrTokenNotValidYet.Error(), err: ErrTokenNotValidYet}
+	}
+
+	if p.opts.maxAge > 0 {
+		if claims.IssuedAt == nil {
+			return &Err{reason: "iat claim missing", err: ErrParseToken}
+		}
+
+		issuedAt := claims.IssuedAt.Time
+		if now.Add(leeway).Before(issuedAt) {
+			return &Err{reason: "token issued in the future", err: ErrTokenNotValidYet}
+		}
+
+		if now.Sub(issuedAt) > p.opts.maxAge+leeway {
+			return &Err{reason: ErrTokenTooOld.Error(), err: ErrTokenTooOld}
+		}
+	}
+
+	if p.opts.issuer != "" && claims.Issuer != p.opts.issuer {
+		return &Err{reason: "iss " + claims.Issuer, err: ErrInvalidIssuer}
+	}
+
+	if p.opts.audience != "" && !slices.Contains(claims.Audience, p.opts.audience) {
+		return &Err{reason: "aud doesn't contain " + p.opts.audience, err: ErrInvalidAudience}
+	}
+
+	return nil
+}
@@ -0,0 +1,360 @@
+package jwt_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/passwordhash/jwt-test-task/pkg/clock"
+	"github.com/passwordhash/jwt-test-task/pkg/jwt"
+)
+
+// testNow is the reference time of the parser tests. It has no fractional
+// seconds, since the time claims are whole seconds.
+var testNow = time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestHMACKey(t *testing.T) *jwt.HMACKey {
+	t.Helper()
+
+	key, err := jwt.NewHMACKey(jwt.HS256, []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewHMACKey() error = %v", err)
+	}
+
+	return key
+}
+
+func TestParseTokenTimeClaims(t *testing.T) {
+	key := newTestHMACKey(t)
+
+	exp := testNow
+	nbf := testNow
+	iat := testNow
+
+	tests := []struct {
+		name    string
+		claims  jwt.RegisteredClaims
+		now     time.Time
+		opts    []jwt.ParseOption
+		wantErr error
+	}{
+		{
+			name:   "before exp",
+			claims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(exp)},
+			now:    exp.Add(-time.Second),
+		},
+		{
+			name:   "exactly at exp",
+			claims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(exp)},
+			now:    exp,
+		},
+		{
+			name:    "one second after exp",
+			claims:  jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(exp)},
+			now:     exp.Add(time.Second),
+			wantErr: jwt.ErrTokenExpired,
+		},
+		{
+			name:   "exp minus leeway",
+			claims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(exp)},
+			now:    exp.Add(-5 * time.Second),
+			opts:   []jwt.ParseOption{jwt.WithLeeway(5 * time.Second)},
+		},
+		{
+			name:   "exp plus leeway",
+			claims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(exp)},
+			now:    exp.Add(5 * time.Second),
+			opts:   []jwt.ParseOption{jwt.WithLeeway(5 * time.Second)},
+		},
+		{
+			name:    "exp plus leeway plus one second",
+			claims:  jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(exp)},
+			now:     exp.Add(6 * time.Second),
+			opts:    []jwt.ParseOption{jwt.WithLeeway(5 * time.Second)},
+			wantErr: jwt.ErrTokenExpired,
+		},
+		{
+			name:    "exp missing",
+			claims:  jwt.RegisteredClaims{Subject: "user"},
+			now:     testNow,
+			wantErr: jwt.ErrParseToken,
+		},
+		{
+			name:   "expired without expiration check",
+			claims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(exp)},
+			now:    exp.Add(time.Hour),
+			opts:   []jwt.ParseOption{jwt.WithoutExpiration()},
+		},
+		{
+			name: "nbf minus one second",
+			claims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(nbf.Add(time.Hour)),
+				NotBefore: jwt.NewNumericDate(nbf),
+			},
+			now:     nbf.Add(-time.Second),
+			wantErr: jwt.ErrTokenNotValidYet,
+		},
+		{
+			name: "exactly at nbf",
+			claims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(nbf.Add(time.Hour)),
+				NotBefore: jwt.NewNumericDate(nbf),
+			},
+			now: nbf,
+		},
+		{
+			name: "nbf minus one second within leeway",
+			claims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(nbf.Add(time.Hour)),
+				NotBefore: jwt.NewNumericDate(nbf),
+			},
+			now:  nbf.Add(-time.Second),
+			opts: []jwt.ParseOption{jwt.WithLeeway(5 * time.Second)},
+		},
+		{
+			name: "nbf minus leeway minus one second",
+			claims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(nbf.Add(time.Hour)),
+				NotBefore: jwt.NewNumericDate(nbf),
+			},
+			now:     nbf.Add(-6 * time.Second),
+			opts:    []jwt.ParseOption{jwt.WithLeeway(5 * time.Second)},
+			wantErr: jwt.ErrTokenNotValidYet,
+		},
+		{
+			name: "exactly at max age",
+			claims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(iat.Add(time.Hour)),
+				IssuedAt:  jwt.NewNumericDate(iat),
+			},
+			now:  iat.Add(time.Minute),
+			opts: []jwt.ParseOption{jwt.WithMaxAge(time.Minute)},
+		},
+		{
+			name: "one second over max age",
+			claims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(iat.Add(time.Hour)),
+				IssuedAt:  jwt.NewNumericDate(iat),
+			},
+			now:     iat.Add(time.Minute + time.Second),
+			opts:    []jwt.ParseOption{jwt.WithMaxAge(time.Minute)},
+			wantErr: jwt.ErrTokenTooOld,
+		},
+		{
+			name: "issued in the future",
+			claims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(iat.Add(time.Hour)),
+				IssuedAt:  jwt.NewNumericDate(iat),
+			},
+			now:     iat.Add(-time.Second),
+			opts:    []jwt.ParseOption{jwt.WithMaxAge(time.Minute)},
+			wantErr: jwt.ErrTokenNotValidYet,
+		},
+		{
+			name:    "max age requires iat",
+			claims:  jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(iat.Add(time.Hour))},
+			now:     iat,
+			opts:    []jwt.ParseOption{jwt.WithMaxAge(time.Minute)},
+			wantErr: jwt.ErrParseToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := jwt.NewTokenFrom(tt.claims, key)
+			if err != nil {
+				t.Fatalf("NewTokenFrom() error = %v", err)
+			}
+
+			opts := append([]jwt.ParseOption{jwt.WithClock(clock.NewFake(tt.now))}, tt.opts...)
+
+			_, err = jwt.ParseToken(token, key, opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewTokenTTL(t *testing.T) {
+	const ttl = time.Minute
+
+	key := newTestHMACKey(t)
+	c := clock.NewFake(testNow)
+
+	token, err := jwt.NewToken(map[string]any{"sub": "user"}, ttl, key, jwt.WithTokenClock(c))
+	if err != nil {
+		t.Fatalf("NewToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		after   time.Duration
+		wantErr error
+	}{
+		{name: "issued", after: 0},
+		{name: "one second before ttl", after: ttl - time.Second},
+		{name: "exactly at ttl", after: ttl},
+		{name: "one second after ttl", after: ttl + time.Second, wantErr: jwt.ErrTokenExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.Set(testNow.Add(tt.after))
+
+			claims, err := jwt.ParseToken(token, key, jwt.WithClock(c))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseToken() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			registered := claims.Registered()
+			if !registered.IssuedAt.Time.Equal(testNow) {
+				t.Errorf("iat = %v, want %v", registered.IssuedAt.Time, testNow)
+			}
+			if !registered.ExpiresAt.Time.Equal(testNow.Add(ttl)) {
+				t.Errorf("exp = %v, want %v", registered.ExpiresAt.Time, testNow.Add(ttl))
+			}
+		})
+	}
+}
+
+// forgeToken builds a token with an arbitrary header, bypassing the checks of
+// NewToken. The signature is made by sign, or left empty when sign is nil.
+func forgeToken(t *testing.T, header map[string]any, sign func([]byte) ([]byte, error)) string {
+	t.Helper()
+
+	encode := func(v any) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("json.Marshal() error = %v", err)
+		}
+
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	signingInput := encode(header) + "." + encode(map[string]any{"sub": "user", "exp": testNow.Add(time.Hour).Unix()})
+	if sign == nil {
+		return signingInput + "."
+	}
+
+	signature, err := sign([]byte(signingInput))
+	if err != nil {
+		t.Fatalf("sign() error = %v", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestParseTokenAlg(t *testing.T) {
+	hmacKey := newTestHMACKey(t)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+
+	rsaPublic, err := jwt.NewRSAVerifyingKey(jwt.RS256, &rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("NewRSAVerifyingKey() error = %v", err)
+	}
+
+	// The classic confusion attack: an HS256 token MACed with the RSA public
+	// key, presented to a verifier that holds that public key.
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey() error = %v", err)
+	}
+
+	confusionKey, err := jwt.NewHMACKey(jwt.HS256, der)
+	if err != nil {
+		t.Fatalf("NewHMACKey() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		key     jwt.VerifyingKey
+		opts    []jwt.ParseOption
+		wantErr error
+	}{
+		{
+			name:  "valid",
+			token: forgeToken(t, map[string]any{"alg": "HS256", "typ": "JWT"}, hmacKey.Sign),
+			key:   hmacKey,
+		},
+		{
+			name:    "alg none",
+			token:   forgeToken(t, map[string]any{"alg": "none", "typ": "JWT"}, nil),
+			key:     hmacKey,
+			wantErr: jwt.ErrUnsupportedAlg,
+		},
+		{
+			name:    "alg none with signature",
+			token:   forgeToken(t, map[string]any{"alg": "none", "typ": "JWT"}, hmacKey.Sign),
+			key:     hmacKey,
+			wantErr: jwt.ErrUnsupportedAlg,
+		},
+		{
+			name:    "unsupported alg",
+			token:   forgeToken(t, map[string]any{"alg": "PS256", "typ": "JWT"}, hmacKey.Sign),
+			key:     hmacKey,
+			wantErr: jwt.ErrUnsupportedAlg,
+		},
+		{
+			name:    "alg header missing",
+			token:   forgeToken(t, map[string]any{"typ": "JWT"}, hmacKey.Sign),
+			key:     hmacKey,
+			wantErr: jwt.ErrInvalidHeader,
+		},
+		{
+			name:    "HS256 with RSA public key",
+			token:   forgeToken(t, map[string]any{"alg": "HS256", "typ": "JWT"}, confusionKey.Sign),
+			key:     rsaPublic,
+			wantErr: jwt.ErrAlgMismatch,
+		},
+		{
+			name:    "HS384 with HS256 key",
+			token:   forgeToken(t, map[string]any{"alg": "HS384", "typ": "JWT"}, hmacKey.Sign),
+			key:     hmacKey,
+			wantErr: jwt.ErrAlgMismatch,
+		},
+		{
+			name:    "alg not in allow-list",
+			token:   forgeToken(t, map[string]any{"alg": "HS256", "typ": "JWT"}, hmacKey.Sign),
+			key:     hmacKey,
+			opts:    []jwt.ParseOption{jwt.WithAllowedAlgs(jwt.RS256, jwt.ES256)},
+			wantErr: jwt.ErrUnsupportedAlg,
+		},
+		{
+			name:  "alg in allow-list",
+			token: forgeToken(t, map[string]any{"alg": "HS256", "typ": "JWT"}, hmacKey.Sign),
+			key:   hmacKey,
+			opts:  []jwt.ParseOption{jwt.WithAllowedAlgs(jwt.RS256, jwt.HS256)},
+		},
+		{
+			name:    "two segments",
+			token:   "eyJhbGciOiJIUzI1NiJ9.e30",
+			key:     hmacKey,
+			wantErr: jwt.ErrParseToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]jwt.ParseOption{jwt.WithClock(clock.NewFake(testNow))}, tt.opts...)
+
+			_, err := jwt.ParseToken(tt.token, tt.key, opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
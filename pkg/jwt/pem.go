@@ -0,0 +1,44 @@
+package jwt
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+)
+
+// ParsePrivateKeyPEM parses a PKCS#8, PKCS#1 (RSA) or SEC 1 (EC) private key
+// in PEM format. The result can be passed to NewSigningKey.
+func ParsePrivateKeyPEM(data []byte) (any, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, &Err{reason: "no PEM block found", err: nil}
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, &Err{reason: "unsupported PEM block type " + block.Type, err: nil}
+	}
+}
+
+// ParsePublicKeyPEM parses a PKIX or PKCS#1 (RSA) public key in PEM format.
+// The result can be passed to NewVerifyingKey.
+func ParsePublicKeyPEM(data []byte) (any, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, &Err{reason: "no PEM block found", err: nil}
+	}
+
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, &Err{reason: "unsupported PEM block type " + block.Type, err: nil}
+	}
+}
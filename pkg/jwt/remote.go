@@ -0,0 +1,270 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRefreshInterval    = 5 * time.Minute
+	defaultMinRefetchInterval = 30 * time.Second
+	defaultFetchTimeout       = 10 * time.Second
+
+	// maxJWKSSize limits the size of a fetched key set.
+	maxJWKSSize = 1 << 20
+)
+
+var (
+	ErrFetchJWKS     = errors.New("failed to fetch JWKS")
+	ErrInvalidOption = errors.New("invalid remote verifier option")
+)
+
+// RemoteVerifier verifies tokens with the keys published at a JWKS URL.
+// The keys are cached and refreshed in the background. A token with an
+// unknown kid triggers one extra fetch, at most once per minimal refetch
+// interval, so a key published after the last refresh is picked up without
+// letting bogus tokens flood the JWKS endpoint.
+//
+// RemoteVerifier is safe for concurrent use. Close stops the background
+// refresh.
+type RemoteVerifier struct {
+	url    string
+	client *http.Client
+
+	refreshInterval    time.Duration
+	minRefetchInterval time.Duration
+	parseOpts          []ParseOption
+
+	mu        sync.RWMutex
+	keys      map[string]VerifyingKey
+	etag      string
+	lastFetch time.Time
+
+	// fetchMu serializes fetches, so concurrent misses cause a single request.
+	fetchMu sync.Mutex
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type RemoteOption func(*RemoteVerifier)
+
+// WithHTTPClient sets the client used to fetch the key set.
+func WithHTTPClient(client *http.Client) RemoteOption {
+	return func(v *RemoteVerifier) {
+		v.client = client
+	}
+}
+
+// WithRefreshInterval sets how often the keys are refreshed in the background.
+// It must be positive.
+func WithRefreshInterval(d time.Duration) RemoteOption {
+	return func(v *RemoteVerifier) {
+		v.refreshInterval = d
+	}
+}
+
+// WithMinRefetchInterval sets the minimal time between fetches caused by an
+// unknown kid. Zero disables the limit; it must not be negative.
+func WithMinRefetchInterval(d time.Duration) RemoteOption {
+	return func(v *RemoteVerifier) {
+		v.minRefetchInterval = d
+	}
+}
+
+// WithParseOptions sets the options applied to every verified token.
+func WithParseOptions(opts ...ParseOption) RemoteOption {
+	return func(v *RemoteVerifier) {
+		v.parseOpts = opts
+	}
+}
+
+// NewRemoteVerifier fetches the key set from url and starts the background
+// refresh. It fails if an option is invalid or the initial fetch fails.
+func NewRemoteVerifier(ctx context.Context, url string, opts ...RemoteOption) (*RemoteVerifier, error) {
+	v := &RemoteVerifier{
+		url:                url,
+		client:             &http.Client{Timeout: defaultFetchTimeout}, //nolint:exhaustruct
+		refreshInterval:    defaultRefreshInterval,
+		minRefetchInterval: defaultMinRefetchInterval,
+		keys:               make(map[string]VerifyingKey),
+		stop:               make(chan struct{}),
+		done:               make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	if v.refreshInterval <= 0 {
+		return nil, &Err{reason: fmt.Sprintf("refresh interval %s is not positive", v.refreshInterval), err: ErrInvalidOption}
+	}
+
+	if v.minRefetchInterval < 0 {
+		return nil, &Err{reason: fmt.Sprintf("minimal refetch interval %s is negative", v.minRefetchInterval), err: ErrInvalidOption}
+	}
+
+	if err := v.fetch(ctx); err != nil {
+		return nil, err
+	}
+
+	go v.refreshLoop()
+
+	return v, nil
+}
+
+// Verify verifies the token and returns its claims.
+func (v *RemoteVerifier) Verify(ctx context.Context, token string) (Payload, error) {
+	return ParseTokenWithKeySet(token, remoteKeySet{v: v, ctx: ctx}, v.parseOpts...)
+}
+
+// Close stops the background refresh.
+func (v *RemoteVerifier) Close() {
+	v.closeOnce.Do(func() {
+		close(v.stop)
+		<-v.done
+	})
+}
+
+type remoteKeySet struct {
+	v   *RemoteVerifier
+	ctx context.Context
+}
+
+func (s remoteKeySet) Key(kid string) (VerifyingKey, error) {
+	return s.v.key(s.ctx, kid)
+}
+
+func (v *RemoteVerifier) key(ctx context.Context, kid string) (VerifyingKey, error) {
+	if key, ok := v.cached(kid); ok {
+		return key, nil
+	}
+
+	v.fetchMu.Lock()
+	defer v.fetchMu.Unlock()
+
+	// The key may have been fetched while waiting for the lock.
+	if key, ok := v.cached(kid); ok {
+		return key, nil
+	}
+
+	v.mu.RLock()
+	lastFetch := v.lastFetch
+	v.mu.RUnlock()
+
+	if time.Since(lastFetch) < v.minRefetchInterval {
+		return nil, &Err{reason: "key " + kid, err: ErrKeyNotFound}
+	}
+
+	if err := v.fetchLocked(ctx); err != nil {
+		return nil, err
+	}
+
+	if key, ok := v.cached(kid); ok {
+		return key, nil
+	}
+
+	return nil, &Err{reason: "key " + kid, err: ErrKeyNotFound}
+}
+
+func (v *RemoteVerifier) cached(kid string) (VerifyingKey, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	key, ok := v.keys[kid]
+
+	return key, ok
+}
+
+func (v *RemoteVerifier) refreshLoop() {
+	defer close(v.done)
+
+	ticker := time.NewTicker(v.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-v.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), v.refreshInterval)
+			// On failure the previous keys stay in use until the next refresh.
+			_ = v.fetch(ctx)
+			cancel()
+		}
+	}
+}
+
+func (v *RemoteVerifier) fetch(ctx context.Context) error {
+	v.fetchMu.Lock()
+	defer v.fetchMu.Unlock()
+
+	return v.fetchLocked(ctx)
+}
+
+// fetchLocked downloads the key set and replaces the cached keys. Keys
+// that can't be used for verification are skipped. It must be called
+// with fetchMu held.
+func (v *RemoteVerifier) fetchLocked(ctx context.Context) error {
+	v.mu.Lock()
+	v.lastFetch = time.Now()
+	etag := v.etag
+	v.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.url, nil)
+	if err != nil {
+		return &Err{reason: err.Error(), err: ErrFetchJWKS}
+	}
+	req.Header.Set("Accept", "application/jwk-set+json, application/json")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return &Err{reason: err.Error(), err: ErrFetchJWKS}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return &Err{reason: fmt.Sprintf("unexpected status code %d", resp.StatusCode), err: ErrFetchJWKS}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+	if err != nil {
+		return &Err{reason: err.Error(), err: ErrFetchJWKS}
+	}
+
+	var set JWKS
+	if err := json.Unmarshal(body, &set); err != nil {
+		return &Err{reason: err.Error(), err: ErrFetchJWKS}
+	}
+
+	keys := make(map[string]VerifyingKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		key, err := jwk.VerifyingKey()
+		if err != nil {
+			continue
+		}
+
+		keys[jwk.Kid] = key
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.etag = resp.Header.Get("ETag")
+	v.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,278 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/passwordhash/jwt-test-task/pkg/jwt"
+)
+
+// jwksServer serves a mutable key set and counts the requests it receives.
+type jwksServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	set     jwt.JWKS
+	fetches atomic.Int32
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	t.Helper()
+
+	s := &jwksServer{set: jwt.JWKS{Keys: []jwt.JWK{}}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		s.fetches.Add(1)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		_ = json.NewEncoder(w).Encode(s.set)
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// publish replaces the served key set with the public parts of keys.
+func (s *jwksServer) publish(t *testing.T, keys map[string]jwt.SigningKey) {
+	t.Helper()
+
+	set := jwt.JWKS{Keys: make([]jwt.JWK, 0, len(keys))}
+	for kid, key := range keys {
+		jwk, err := jwt.NewJWK(kid, key.Public())
+		if err != nil {
+			t.Fatalf("NewJWK(%q) error = %v", kid, err)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	s.mu.Lock()
+	s.set = set
+	s.mu.Unlock()
+}
+
+func newEd25519Key(t *testing.T) jwt.SigningKey {
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	key, err := jwt.NewEd25519SigningKey(jwt.EdDSA, priv)
+	if err != nil {
+		t.Fatalf("NewEd25519SigningKey() error = %v", err)
+	}
+
+	return key
+}
+
+func signWithKID(t *testing.T, kid string, key jwt.SigningKey) string {
+	t.Helper()
+
+	token, err := jwt.NewToken(map[string]any{"sub": "user"}, time.Minute, jwt.WithKID(kid, key))
+	if err != nil {
+		t.Fatalf("NewToken() error = %v", err)
+	}
+
+	return token
+}
+
+func TestRemoteVerifierFetchesKeys(t *testing.T) {
+	srv := newJWKSServer(t)
+	key := newEd25519Key(t)
+	srv.publish(t, map[string]jwt.SigningKey{"a": key})
+
+	v, err := jwt.NewRemoteVerifier(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("NewRemoteVerifier() error = %v", err)
+	}
+	defer v.Close()
+
+	token := signWithKID(t, "a", key)
+	for range 3 {
+		claims, err := v.Verify(context.Background(), token)
+		if err != nil {
+			t.Fatalf("Verify() error = %v", err)
+		}
+		if claims["sub"] != "user" {
+			t.Errorf("sub = %v, want user", claims["sub"])
+		}
+	}
+
+	if got := srv.fetches.Load(); got != 1 {
+		t.Errorf("fetches = %d, want 1, known keys must be served from the cache", got)
+	}
+
+	_, err = v.Verify(context.Background(), signWithKID(t, "a", newEd25519Key(t)))
+	if !errors.Is(err, jwt.ErrParseToken) {
+		t.Errorf("Verify() with a foreign key error = %v, want %v", err, jwt.ErrParseToken)
+	}
+}
+
+func TestRemoteVerifierInitialFetchFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	_, err := jwt.NewRemoteVerifier(context.Background(), srv.URL)
+	if !errors.Is(err, jwt.ErrFetchJWKS) {
+		t.Fatalf("NewRemoteVerifier() error = %v, want %v", err, jwt.ErrFetchJWKS)
+	}
+}
+
+func TestRemoteVerifierInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []jwt.RemoteOption
+	}{
+		{name: "zero refresh interval", opts: []jwt.RemoteOption{jwt.WithRefreshInterval(0)}},
+		{name: "negative refresh interval", opts: []jwt.RemoteOption{jwt.WithRefreshInterval(-time.Second)}},
+		{name: "negative min refetch interval", opts: []jwt.RemoteOption{jwt.WithMinRefetchInterval(-time.Second)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newJWKSServer(t)
+
+			_, err := jwt.NewRemoteVerifier(context.Background(), srv.URL, tt.opts...)
+			if !errors.Is(err, jwt.ErrInvalidOption) {
+				t.Fatalf("NewRemoteVerifier() error = %v, want %v", err, jwt.ErrInvalidOption)
+			}
+
+			if got := srv.fetches.Load(); got != 0 {
+				t.Errorf("fetches = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestRemoteVerifierUnknownKID(t *testing.T) {
+	oldKey, newKey := newEd25519Key(t), newEd25519Key(t)
+
+	tests := []struct {
+		name        string
+		minRefetch  time.Duration
+		wantErr     error
+		wantFetches int32
+	}{
+		{name: "refetched", minRefetch: 0, wantErr: nil, wantFetches: 2},
+		{name: "rate limited", minRefetch: time.Hour, wantErr: jwt.ErrKeyNotFound, wantFetches: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newJWKSServer(t)
+			srv.publish(t, map[string]jwt.SigningKey{"old": oldKey})
+
+			v, err := jwt.NewRemoteVerifier(
+				context.Background(),
+				srv.URL,
+				jwt.WithMinRefetchInterval(tt.minRefetch),
+			)
+			if err != nil {
+				t.Fatalf("NewRemoteVerifier() error = %v", err)
+			}
+			defer v.Close()
+
+			// The key is published after the verifier has cached the set.
+			srv.publish(t, map[string]jwt.SigningKey{"old": oldKey, "new": newKey})
+
+			_, err = v.Verify(context.Background(), signWithKID(t, "new", newKey))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got := srv.fetches.Load(); got != tt.wantFetches {
+				t.Errorf("fetches = %d, want %d", got, tt.wantFetches)
+			}
+		})
+	}
+}
+
+func TestRemoteVerifierUnknownKIDSingleFetch(t *testing.T) {
+	srv := newJWKSServer(t)
+	srv.publish(t, map[string]jwt.SigningKey{"a": newEd25519Key(t)})
+
+	v, err := jwt.NewRemoteVerifier(context.Background(), srv.URL, jwt.WithMinRefetchInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("NewRemoteVerifier() error = %v", err)
+	}
+	defer v.Close()
+
+	token := signWithKID(t, "bogus", newEd25519Key(t))
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = v.Verify(context.Background(), token)
+		}()
+	}
+	wg.Wait()
+
+	if got := srv.fetches.Load(); got != 1 {
+		t.Errorf("fetches = %d, want 1", got)
+	}
+}
+
+func TestRemoteVerifierRefreshInterval(t *testing.T) {
+	srv := newJWKSServer(t)
+	retired, active := newEd25519Key(t), newEd25519Key(t)
+	srv.publish(t, map[string]jwt.SigningKey{"retired": retired})
+
+	v, err := jwt.NewRemoteVerifier(
+		context.Background(),
+		srv.URL,
+		jwt.WithRefreshInterval(20*time.Millisecond),
+		jwt.WithMinRefetchInterval(time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("NewRemoteVerifier() error = %v", err)
+	}
+	defer v.Close()
+
+	retiredToken := signWithKID(t, "retired", retired)
+	if _, err := v.Verify(context.Background(), retiredToken); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	srv.publish(t, map[string]jwt.SigningKey{"active": active})
+
+	// Unknown kids are rate limited, so only the background refresh can
+	// pick up the new set once the cached one is stale.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		_, err := v.Verify(context.Background(), retiredToken)
+		if errors.Is(err, jwt.ErrKeyNotFound) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("retired key still accepted after the refresh interval, err = %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := v.Verify(context.Background(), signWithKID(t, "active", active)); err != nil {
+		t.Errorf("Verify() with the refreshed key error = %v", err)
+	}
+
+	v.Close()
+	fetches := srv.fetches.Load()
+	time.Sleep(60 * time.Millisecond)
+	if got := srv.fetches.Load(); got != fetches {
+		t.Errorf("fetches after Close = %d, want %d", got, fetches)
+	}
+}
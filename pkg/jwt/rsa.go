@@ -0,0 +1,93 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+)
+
+// minRSAKeyBits is the smallest RSA modulus accepted for signing and
+// verification.
+const minRSAKeyBits = 2048
+
+var (
+	_ SigningKey   = (*RSASigningKey)(nil)
+	_ VerifyingKey = (*RSAVerifyingKey)(nil)
+)
+
+// RSASigningKey signs tokens with RS256, RS384 or RS512 (RSASSA-PKCS1-v1_5).
+type RSASigningKey struct {
+	alg Alg
+	key *rsa.PrivateKey
+}
+
+func NewRSASigningKey(alg Alg, key *rsa.PrivateKey) (*RSASigningKey, error) {
+	if key == nil {
+		return nil, &Err{reason: "nil RSA key", err: ErrInvalidKey}
+	}
+
+	if err := checkRSA(alg, &key.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return &RSASigningKey{alg: alg, key: key}, nil
+}
+
+func (k *RSASigningKey) Alg() Alg {
+	return k.alg
+}
+
+func (k *RSASigningKey) Sign(signingInput []byte) ([]byte, error) {
+	h, _ := hashFor(k.alg)
+
+	return rsa.SignPKCS1v15(rand.Reader, k.key, h, digest(h, signingInput))
+}
+
+func (k *RSASigningKey) Public() VerifyingKey {
+	return &RSAVerifyingKey{alg: k.alg, key: &k.key.PublicKey}
+}
+
+// RSAVerifyingKey verifies RS256, RS384 or RS512 signatures.
+type RSAVerifyingKey struct {
+	alg Alg
+	key *rsa.PublicKey
+}
+
+func NewRSAVerifyingKey(alg Alg, key *rsa.PublicKey) (*RSAVerifyingKey, error) {
+	if err := checkRSA(alg, key); err != nil {
+		return nil, err
+	}
+
+	return &RSAVerifyingKey{alg: alg, key: key}, nil
+}
+
+func (k *RSAVerifyingKey) Alg() Alg {
+	return k.alg
+}
+
+func (k *RSAVerifyingKey) Verify(signingInput, signature []byte) error {
+	h, _ := hashFor(k.alg)
+
+	if err := rsa.VerifyPKCS1v15(k.key, h, digest(h, signingInput), signature); err != nil {
+		return errInvalidSignature()
+	}
+
+	return nil
+}
+
+func checkRSA(alg Alg, key *rsa.PublicKey) error {
+	switch alg {
+	case RS256, RS384, RS512:
+	default:
+		return &Err{reason: "algorithm " + string(alg) + " is not an RSA algorithm", err: ErrAlgMismatch}
+	}
+
+	if key == nil || key.N == nil {
+		return &Err{reason: "nil RSA key", err: ErrInvalidKey}
+	}
+
+	if key.N.BitLen() < minRSAKeyBits {
+		return &Err{reason: "RSA key is too short", err: ErrInvalidKey}
+	}
+
+	return nil
+}